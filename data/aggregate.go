@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// --- Flux aggregation endpoint with response caching ---
+//
+// handleAggregateData lets a caller ask for a downsampled view of stored
+// OHLCV (e.g. weekly high, hourly mean volume) without hand-writing Flux.
+// Responses are cached in-process, keyed on the request parameters, since
+// dashboards tend to poll the same window repeatedly.
+
+// aggregateCacheTTL bounds how long a computed aggregate is served from
+// cache before the next request re-runs the Flux query.
+const aggregateCacheTTL = 30 * time.Second
+
+// aggregateCacheMaxBytes bounds the in-process cache's total response size.
+const aggregateCacheMaxBytes = 32 * 1024 * 1024
+
+// AggregateRequest is the body of POST /v1/data/aggregate.
+type AggregateRequest struct {
+	Ticker string `json:"ticker"`
+	Window string `json:"window"` // Flux duration literal, e.g. "1h", "1d", "1w"
+	Agg    string `json:"agg"`    // "mean", "sum", "first", "last", "min", "max", or "ohlc"
+	From   string `json:"from"`   // RFC3339 or Flux relative duration, e.g. "-30d"
+	To     string `json:"to"`     // RFC3339 or Flux relative duration, e.g. "now()"
+}
+
+// AggregatePoint is a single aggregated bucket. Field names the stored field
+// the value was reduced from (for "ohlc": open, high, low or close); one
+// point is emitted per field per window.
+type AggregatePoint struct {
+	Time  string  `json:"time"`
+	Field string  `json:"field,omitempty"`
+	Value float64 `json:"value"`
+}
+
+type AggregateResponse struct {
+	Ticker string           `json:"ticker"`
+	Window string           `json:"window"`
+	Agg    string           `json:"agg"`
+	Points []AggregatePoint `json:"points"`
+}
+
+var aggregateFuncs = map[string]string{
+	"mean":  "mean",
+	"sum":   "sum",
+	"first": "first",
+	"last":  "last",
+	"min":   "min",
+	"max":   "max",
+}
+
+func (s *Server) handleAggregateData(c *gin.Context) {
+	var req AggregateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+	if req.Window == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Window is required"})
+		return
+	}
+	if req.Agg == "" {
+		req.Agg = "mean"
+	}
+	if req.From == "" {
+		req.From = "-30d"
+	}
+	if req.To == "" {
+		req.To = "now()"
+	}
+	if req.Agg != "ohlc" {
+		if _, ok := aggregateFuncs[req.Agg]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported agg", "details": req.Agg})
+			return
+		}
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|%s|%s|%s", req.Ticker, req.Window, req.Agg, req.From, req.To)
+
+	if cached, etag, ok := s.AggregateCache.Get(cacheKey); ok {
+		if c.GetHeader("If-None-Match") == etag {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Header("ETag", etag)
+		c.Data(http.StatusOK, "application/json", cached)
+		return
+	}
+
+	var points []AggregatePoint
+	var err error
+	if req.Agg == "ohlc" {
+		points, err = s.queryOHLCAggregate(req)
+	} else {
+		points, err = s.queryAggregate(req)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Query failed", "details": err.Error()})
+		return
+	}
+
+	resp := AggregateResponse{Ticker: req.Ticker, Window: req.Window, Agg: req.Agg, Points: points}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response", "details": err.Error()})
+		return
+	}
+
+	etag := s.AggregateCache.Set(cacheKey, body, aggregateCacheTTL)
+	c.Header("ETag", etag)
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+// queryAggregate runs a single aggregateWindow reduction over every stored
+// field of the ticker, returning one point per (field, window). Results
+// whose value is not a float64 (e.g. a sum of the integer volume field) are
+// skipped.
+func (s *Server) queryAggregate(req AggregateRequest) ([]AggregatePoint, error) {
+	fn := aggregateFuncs[req.Agg]
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+		  |> range(start: %s, stop: %s)
+		  |> filter(fn: (r) => r._measurement == "stock_prices")
+		  |> filter(fn: (r) => r.ticker == "%s")
+		  |> aggregateWindow(every: %s, fn: %s, createEmpty: false)
+		  |> sort(columns: ["_time"], desc: false)
+	`, influxBucket, fluxTimeLiteral(req.From), fluxTimeLiteral(req.To), req.Ticker, req.Window, fn)
+
+	result, err := s.QueryAPI.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []AggregatePoint
+	for result.Next() {
+		record := result.Record()
+		value, ok := record.Value().(float64)
+		if !ok {
+			continue
+		}
+		points = append(points, AggregatePoint{
+			Time:  record.Time().Format(time.RFC3339),
+			Field: record.Field(),
+			Value: value,
+		})
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	return points, nil
+}
+
+// queryOHLCAggregate builds one aggregateWindow reduction per OHLC field
+// (open: first, high: max, low: min, close: last) and merges them into a
+// single time-sorted slice of points.
+func (s *Server) queryOHLCAggregate(req AggregateRequest) ([]AggregatePoint, error) {
+	ohlcFuncs := []struct {
+		field string
+		fn    string
+	}{
+		{"open", "first"},
+		{"high", "max"},
+		{"low", "min"},
+		{"close", "last"},
+	}
+
+	var points []AggregatePoint
+	for _, oc := range ohlcFuncs {
+		query := fmt.Sprintf(`
+			from(bucket: "%s")
+			  |> range(start: %s, stop: %s)
+			  |> filter(fn: (r) => r._measurement == "stock_prices")
+			  |> filter(fn: (r) => r.ticker == "%s")
+			  |> filter(fn: (r) => r._field == "%s")
+			  |> aggregateWindow(every: %s, fn: %s, createEmpty: false)
+			  |> sort(columns: ["_time"], desc: false)
+		`, influxBucket, fluxTimeLiteral(req.From), fluxTimeLiteral(req.To), req.Ticker, oc.field, req.Window, oc.fn)
+
+		result, err := s.QueryAPI.Query(context.Background(), query)
+		if err != nil {
+			return nil, err
+		}
+		for result.Next() {
+			record := result.Record()
+			value, ok := record.Value().(float64)
+			if !ok {
+				continue
+			}
+			points = append(points, AggregatePoint{
+				Time:  record.Time().Format(time.RFC3339),
+				Field: oc.field,
+				Value: value,
+			})
+		}
+		if result.Err() != nil {
+			return nil, result.Err()
+		}
+	}
+	return points, nil
+}
+
+// fluxTimeLiteral passes relative durations (e.g. "-30d", "now()") through
+// unchanged and wraps absolute RFC3339 timestamps in the time() constructor
+// Flux expects for range() bounds.
+func fluxTimeLiteral(value string) string {
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return fmt.Sprintf("time(v: %q)", value)
+	}
+	return value
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFluxTimeLiteral_PassesRelativeDurationThrough(t *testing.T) {
+	assert.Equal(t, "-30d", fluxTimeLiteral("-30d"))
+	assert.Equal(t, "now()", fluxTimeLiteral("now()"))
+}
+
+func TestFluxTimeLiteral_WrapsAbsoluteTimestamp(t *testing.T) {
+	out := fluxTimeLiteral("2020-01-01T00:00:00Z")
+	assert.Equal(t, `time(v: "2020-01-01T00:00:00Z")`, out)
+}
+
+func TestHandleAggregateData_RejectsMissingTicker(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{AggregateCache: NewLRUCache(1024)}
+	router := gin.Default()
+	router.POST("/v1/data/aggregate", server.handleAggregateData)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/aggregate", strings.NewReader(`{"window":"1d"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleAggregateData_RejectsUnsupportedAgg(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{AggregateCache: NewLRUCache(1024)}
+	router := gin.Default()
+	router.POST("/v1/data/aggregate", server.handleAggregateData)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/aggregate", strings.NewReader(`{"ticker":"SPY","window":"1d","agg":"nonsense"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleAggregateData_ServesFromCacheWithoutQuerying(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache := NewLRUCache(1024)
+	cacheKey := "SPY|1d|mean|-30d|now()"
+	body := `{"ticker":"SPY","window":"1d","agg":"mean","points":[]}`
+	etag := cache.Set(cacheKey, []byte(body), aggregateCacheTTL)
+
+	server := &Server{AggregateCache: cache}
+	router := gin.Default()
+	router.POST("/v1/data/aggregate", server.handleAggregateData)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/aggregate", strings.NewReader(`{"ticker":"SPY","window":"1d","agg":"mean"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.JSONEq(t, body, rec.Body.String())
+}
+
+func TestHandleAggregateData_IfNoneMatchReturns304(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache := NewLRUCache(1024)
+	cacheKey := "SPY|1d|mean|-30d|now()"
+	body := `{"ticker":"SPY","window":"1d","agg":"mean","points":[]}`
+	etag := cache.Set(cacheKey, []byte(body), aggregateCacheTTL)
+
+	server := &Server{AggregateCache: cache}
+	router := gin.Default()
+	router.POST("/v1/data/aggregate", server.handleAggregateData)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/aggregate", strings.NewReader(`{"ticker":"SPY","window":"1d","agg":"mean"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-None-Match", etag)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
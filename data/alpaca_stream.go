@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// --- Alpaca live streaming ingestion ---
+//
+// AlpacaStreamClient is the mirror image of StreamManager: instead of
+// serving websocket clients, it *is* a websocket client of Alpaca's
+// market-data stream, authenticating and subscribing to bars/trades/quotes,
+// then writing every decoded message into InfluxDB through a small batched,
+// non-blocking buffer so a burst of ticks can't stall the read loop.
+
+const (
+	alpacaStreamURL         = "wss://stream.data.alpaca.markets/v2/iex"
+	alpacaStreamPingPeriod  = 30 * time.Second
+	alpacaStreamPongWait    = 60 * time.Second
+	alpacaStreamWriteWait   = 10 * time.Second
+	alpacaStreamBatchSize   = 500
+	alpacaStreamBatchPeriod = 1 * time.Second
+	alpacaStreamBufferSize  = 4096
+	alpacaStreamMinBackoff  = 1 * time.Second
+	alpacaStreamMaxBackoff  = 30 * time.Second
+)
+
+// alpacaControlMessage is sent to authenticate and to (un)subscribe.
+type alpacaControlMessage struct {
+	Action string   `json:"action"`
+	Key    string   `json:"key,omitempty"`
+	Secret string   `json:"secret,omitempty"`
+	Bars   []string `json:"bars,omitempty"`
+	Trades []string `json:"trades,omitempty"`
+	Quotes []string `json:"quotes,omitempty"`
+}
+
+// alpacaStreamMessage is the loosely-typed shape of an incoming stream
+// message; fields irrelevant to its "T" tag are simply left zero.
+type alpacaStreamMessage struct {
+	Type      string  `json:"T"`
+	Symbol    string  `json:"S"`
+	Open      float64 `json:"o"`
+	High      float64 `json:"h"`
+	Low       float64 `json:"l"`
+	Close     float64 `json:"c"`
+	Volume    float64 `json:"v"`
+	Price     float64 `json:"p"`  // trade price
+	Size      float64 `json:"s"`  // trade size
+	BidPrice  float64 `json:"bp"` // quote bid
+	BidSize   float64 `json:"bs"`
+	AskPrice  float64 `json:"ap"` // quote ask
+	AskSize   float64 `json:"as"`
+	Timestamp string  `json:"t"`
+}
+
+// StreamSubscriptionRequest is the body of both POST /v1/stream/subscribe
+// and POST /v1/stream/unsubscribe.
+type StreamSubscriptionRequest struct {
+	Bars   []string `json:"bars,omitempty"`
+	Trades []string `json:"trades,omitempty"`
+	Quotes []string `json:"quotes,omitempty"`
+}
+
+// StreamStatusResponse reports the live subscription set.
+type StreamStatusResponse struct {
+	Connected bool     `json:"connected"`
+	Bars      []string `json:"bars"`
+	Trades    []string `json:"trades"`
+	Quotes    []string `json:"quotes"`
+}
+
+// AlpacaStreamClient owns the outbound connection plus the live subscription
+// set mutated by the /v1/stream/* endpoints.
+type AlpacaStreamClient struct {
+	APIKeyID    string
+	APISecret   string
+	WriteAPI    api.WriteAPIBlocking
+	Instruments *InstrumentCatalog
+
+	mu        sync.Mutex
+	bars      map[string]bool
+	trades    map[string]bool
+	quotes    map[string]bool
+	connected bool
+
+	connMu sync.Mutex
+	conn   *websocket.Conn
+
+	batch     chan *write.Point
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewAlpacaStreamClient(writeAPI api.WriteAPIBlocking) *AlpacaStreamClient {
+	return &AlpacaStreamClient{
+		APIKeyID:  os.Getenv("ALPACA_API_KEY_ID"),
+		APISecret: os.Getenv("ALPACA_API_SECRET_KEY"),
+		WriteAPI:  writeAPI,
+		bars:      make(map[string]bool),
+		trades:    make(map[string]bool),
+		quotes:    make(map[string]bool),
+		batch:     make(chan *write.Point, alpacaStreamBufferSize),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start launches the batch writer and the reconnecting read loop. It
+// returns immediately; both goroutines run until Stop is called or ctx is
+// canceled, except that the read loop exits at once when the Alpaca API
+// key or secret is unset.
+func (a *AlpacaStreamClient) Start(ctx context.Context) {
+	go a.batchWriter(ctx)
+	go a.connectLoop(ctx)
+}
+
+// Stop closes the current connection and stops the batch writer. Safe to
+// call more than once; intended to be tied to the same shutdown path as
+// influxClient.Close().
+func (a *AlpacaStreamClient) Stop() {
+	a.closeOnce.Do(func() {
+		close(a.done)
+		a.connMu.Lock()
+		if a.conn != nil {
+			a.conn.Close()
+		}
+		a.connMu.Unlock()
+	})
+}
+
+// Subscribe adds symbols to the live subscription set and, if connected,
+// sends an incremental subscribe message upstream.
+func (a *AlpacaStreamClient) Subscribe(req StreamSubscriptionRequest) {
+	a.mu.Lock()
+	for _, s := range req.Bars {
+		a.bars[s] = true
+	}
+	for _, s := range req.Trades {
+		a.trades[s] = true
+	}
+	for _, s := range req.Quotes {
+		a.quotes[s] = true
+	}
+	a.mu.Unlock()
+
+	a.sendControl(alpacaControlMessage{Action: "subscribe", Bars: req.Bars, Trades: req.Trades, Quotes: req.Quotes})
+}
+
+// Unsubscribe removes symbols from the live subscription set and, if
+// connected, sends an unsubscribe message upstream.
+func (a *AlpacaStreamClient) Unsubscribe(req StreamSubscriptionRequest) {
+	a.mu.Lock()
+	for _, s := range req.Bars {
+		delete(a.bars, s)
+	}
+	for _, s := range req.Trades {
+		delete(a.trades, s)
+	}
+	for _, s := range req.Quotes {
+		delete(a.quotes, s)
+	}
+	a.mu.Unlock()
+
+	a.sendControl(alpacaControlMessage{Action: "unsubscribe", Bars: req.Bars, Trades: req.Trades, Quotes: req.Quotes})
+}
+
+// Status reports the current connection state and subscription set.
+func (a *AlpacaStreamClient) Status() StreamStatusResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return StreamStatusResponse{
+		Connected: a.connected,
+		Bars:      keys(a.bars),
+		Trades:    keys(a.trades),
+		Quotes:    keys(a.quotes),
+	}
+}
+
+func keys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// sendControl writes a control message to the current connection, if any.
+// It's a best-effort send: if we're mid-reconnect the new connection's
+// resubscribe-on-connect logic in runConnection will pick up the change.
+func (a *AlpacaStreamClient) sendControl(msg alpacaControlMessage) {
+	a.connMu.Lock()
+	defer a.connMu.Unlock()
+	if a.conn == nil {
+		return
+	}
+	a.conn.SetWriteDeadline(time.Now().Add(alpacaStreamWriteWait))
+	if err := a.conn.WriteJSON(msg); err != nil {
+		slog.Warn("failed to send alpaca stream control message", "action", msg.Action, "error", err)
+	}
+}
+
+// connectLoop dials, authenticates, resubscribes, and reads until the
+// connection drops, then reconnects with exponential backoff until Stop is
+// called or ctx is canceled.
+func (a *AlpacaStreamClient) connectLoop(ctx context.Context) {
+	backoff := alpacaStreamMinBackoff
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		if a.APIKeyID == "" || a.APISecret == "" {
+			slog.Error("ALPACA_API_KEY_ID / ALPACA_API_SECRET_KEY not set, alpaca stream disabled")
+			return
+		}
+
+		if err := a.runConnection(ctx); err != nil {
+			slog.Warn("alpaca stream connection dropped", "error", err, "retry_in", backoff)
+		}
+
+		a.mu.Lock()
+		a.connected = false
+		a.mu.Unlock()
+
+		select {
+		case <-a.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > alpacaStreamMaxBackoff {
+			backoff = alpacaStreamMaxBackoff
+		}
+	}
+}
+
+// runConnection dials once, authenticates, subscribes to the current
+// subscription set, and reads messages until the connection errors out or
+// is closed by Stop.
+func (a *AlpacaStreamClient) runConnection(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, alpacaStreamURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial alpaca stream: %w", err)
+	}
+
+	a.connMu.Lock()
+	a.conn = conn
+	a.connMu.Unlock()
+	defer func() {
+		conn.Close()
+		a.connMu.Lock()
+		a.conn = nil
+		a.connMu.Unlock()
+	}()
+
+	if err := conn.WriteJSON(alpacaControlMessage{Action: "auth", Key: a.APIKeyID, Secret: a.APISecret}); err != nil {
+		return fmt.Errorf("failed to send auth message: %w", err)
+	}
+
+	a.mu.Lock()
+	sub := alpacaControlMessage{Action: "subscribe", Bars: keys(a.bars), Trades: keys(a.trades), Quotes: keys(a.quotes)}
+	a.connected = true
+	a.mu.Unlock()
+	if len(sub.Bars)+len(sub.Trades)+len(sub.Quotes) > 0 {
+		if err := conn.WriteJSON(sub); err != nil {
+			return fmt.Errorf("failed to send subscribe message: %w", err)
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(alpacaStreamPongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(alpacaStreamPongWait))
+		return nil
+	})
+
+	readDone := make(chan struct{})
+	go a.pingLoop(conn, readDone)
+	defer close(readDone)
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		a.handleMessage(raw)
+	}
+}
+
+func (a *AlpacaStreamClient) pingLoop(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(alpacaStreamPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			a.connMu.Lock()
+			conn.SetWriteDeadline(time.Now().Add(alpacaStreamWriteWait))
+			err := conn.WriteMessage(websocket.PingMessage, nil)
+			a.connMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleMessage decodes one frame (a JSON array of tagged messages) and
+// enqueues the resulting InfluxDB points for the batch writer.
+func (a *AlpacaStreamClient) handleMessage(raw []byte) {
+	var msgs []alpacaStreamMessage
+	if err := json.Unmarshal(raw, &msgs); err != nil {
+		slog.Warn("invalid alpaca stream frame", "error", err)
+		return
+	}
+	for _, msg := range msgs {
+		point := alpacaMessageToPoint(msg)
+		if point == nil {
+			continue
+		}
+		select {
+		case a.batch <- point:
+		default:
+			slog.Warn("alpaca stream batch buffer full, dropping point", "ticker", msg.Symbol, "type", msg.Type)
+		}
+	}
+}
+
+// alpacaMessageToPoint converts a single decoded stream message into an
+// InfluxDB point, or returns nil for message types we don't persist (auth
+// acks, subscription confirmations, errors).
+func alpacaMessageToPoint(msg alpacaStreamMessage) *write.Point {
+	ts, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+	if err != nil {
+		ts = time.Now()
+	}
+
+	switch msg.Type {
+	case "b": // bar
+		return influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{"ticker": msg.Symbol},
+			map[string]interface{}{
+				"open":      msg.Open,
+				"high":      msg.High,
+				"low":       msg.Low,
+				"close":     msg.Close,
+				"adj_close": msg.Close,
+				"volume":    int64(msg.Volume),
+			},
+			ts,
+		)
+	case "t": // trade
+		return influxdb2.NewPoint(
+			"stock_trades",
+			map[string]string{"ticker": msg.Symbol},
+			map[string]interface{}{"price": msg.Price, "size": msg.Size},
+			ts,
+		)
+	case "q": // quote
+		return influxdb2.NewPoint(
+			"stock_quotes",
+			map[string]string{"ticker": msg.Symbol},
+			map[string]interface{}{
+				"bid_price": msg.BidPrice,
+				"bid_size":  msg.BidSize,
+				"ask_price": msg.AskPrice,
+				"ask_size":  msg.AskSize,
+			},
+			ts,
+		)
+	default:
+		return nil
+	}
+}
+
+// batchWriter accumulates points from the batch channel and flushes them to
+// InfluxDB either when alpacaStreamBatchSize is reached or every
+// alpacaStreamBatchPeriod, whichever comes first, so a burst of ticks never
+// blocks the websocket read loop on a slow write.
+func (a *AlpacaStreamClient) batchWriter(ctx context.Context) {
+	ticker := time.NewTicker(alpacaStreamBatchPeriod)
+	defer ticker.Stop()
+
+	var pending []*write.Point
+	flush := func() {
+		if len(pending) == 0 || a.WriteAPI == nil {
+			return
+		}
+		quantizePoints(a.Instruments, pending)
+		if err := a.WriteAPI.WritePoint(ctx, pending...); err != nil {
+			slog.Error("failed to write alpaca stream batch", "points", len(pending), "error", err)
+		}
+		pending = nil
+	}
+
+	for {
+		select {
+		case <-a.done:
+			flush()
+			return
+		case <-ctx.Done():
+			flush()
+			return
+		case p := <-a.batch:
+			pending = append(pending, p)
+			if len(pending) >= alpacaStreamBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// --- HTTP handlers ---
+
+func (s *Server) handleStreamSubscribe(c *gin.Context) {
+	var req StreamSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	s.AlpacaStream.Subscribe(req)
+	c.JSON(http.StatusOK, s.AlpacaStream.Status())
+}
+
+func (s *Server) handleStreamUnsubscribe(c *gin.Context) {
+	var req StreamSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	s.AlpacaStream.Unsubscribe(req)
+	c.JSON(http.StatusOK, s.AlpacaStream.Status())
+}
+
+func (s *Server) handleStreamStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.AlpacaStream.Status())
+}
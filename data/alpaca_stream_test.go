@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlpacaMessageToPoint_Bar(t *testing.T) {
+	msg := alpacaStreamMessage{Type: "b", Symbol: "SPY", Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 1000, Timestamp: "2023-01-01T00:00:00Z"}
+	point := alpacaMessageToPoint(msg)
+	assert.NotNil(t, point)
+}
+
+func TestAlpacaMessageToPoint_Trade(t *testing.T) {
+	msg := alpacaStreamMessage{Type: "t", Symbol: "SPY", Price: 420.5, Size: 10, Timestamp: "2023-01-01T00:00:00Z"}
+	point := alpacaMessageToPoint(msg)
+	assert.NotNil(t, point)
+}
+
+func TestAlpacaMessageToPoint_Quote(t *testing.T) {
+	msg := alpacaStreamMessage{Type: "q", Symbol: "SPY", BidPrice: 420, AskPrice: 420.1, Timestamp: "2023-01-01T00:00:00Z"}
+	point := alpacaMessageToPoint(msg)
+	assert.NotNil(t, point)
+}
+
+func TestAlpacaMessageToPoint_UnknownTypeIsNil(t *testing.T) {
+	msg := alpacaStreamMessage{Type: "success", Symbol: "SPY"}
+	assert.Nil(t, alpacaMessageToPoint(msg))
+}
+
+func TestAlpacaStreamClient_SubscribeAndStatus(t *testing.T) {
+	client := NewAlpacaStreamClient(nil)
+	client.Subscribe(StreamSubscriptionRequest{Bars: []string{"SPY"}, Trades: []string{"AAPL"}})
+
+	status := client.Status()
+	assert.ElementsMatch(t, []string{"SPY"}, status.Bars)
+	assert.ElementsMatch(t, []string{"AAPL"}, status.Trades)
+	assert.False(t, status.Connected)
+}
+
+func TestAlpacaStreamClient_UnsubscribeRemoves(t *testing.T) {
+	client := NewAlpacaStreamClient(nil)
+	client.Subscribe(StreamSubscriptionRequest{Bars: []string{"SPY", "QQQ"}})
+	client.Unsubscribe(StreamSubscriptionRequest{Bars: []string{"SPY"}})
+
+	status := client.Status()
+	assert.ElementsMatch(t, []string{"QQQ"}, status.Bars)
+}
+
+func TestAlpacaStreamClient_HandleMessageEnqueuesKnownTypes(t *testing.T) {
+	client := NewAlpacaStreamClient(nil)
+	raw := []byte(`[{"T":"b","S":"SPY","o":1,"h":2,"l":0.5,"c":1.5,"v":100,"t":"2023-01-01T00:00:00Z"},{"T":"success"}]`)
+
+	client.handleMessage(raw)
+
+	select {
+	case p := <-client.batch:
+		assert.NotNil(t, p)
+	case <-time.After(time.Second):
+		t.Fatal("expected a point on the batch channel")
+	}
+	select {
+	case <-client.batch:
+		t.Fatal("unexpected second point from an unsupported message type")
+	default:
+	}
+}
+
+func TestHandleStreamStatus_ReportsSubscriptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client := NewAlpacaStreamClient(nil)
+	client.Subscribe(StreamSubscriptionRequest{Bars: []string{"SPY"}})
+	server := &Server{AlpacaStream: client}
+	router := gin.Default()
+	router.GET("/v1/stream/status", server.handleStreamStatus)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/stream/status", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "SPY")
+}
+
+func TestHandleStreamSubscribe_MutatesSubscriptionSet(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{AlpacaStream: NewAlpacaStreamClient(nil)}
+	router := gin.Default()
+	router.POST("/v1/stream/subscribe", server.handleStreamSubscribe)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/stream/subscribe", strings.NewReader(`{"bars":["SPY"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.ElementsMatch(t, []string{"SPY"}, server.AlpacaStream.Status().Bars)
+}
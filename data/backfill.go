@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+// --- Chunked historical backfill ---
+//
+// Yahoo (and several other providers) silently truncate long date ranges,
+// especially for intraday intervals, so a multi-year backfill has to be
+// split into fixed-size windows and fetched window-by-window. Progress is
+// persisted to an InfluxDB `_backfill_state` measurement keyed by
+// (ticker, interval) so a job interrupted mid-run resumes from the last
+// successfully-written window instead of starting over. Per-provider rate
+// limiting is already shared across every caller of a given provider (see
+// providerLimiter in provider.go), so parallel tickers within a backfill
+// naturally share the same token bucket.
+
+const backfillStateMeasurement = "_backfill_state"
+
+// backfillWindow returns the chunk size to request per call for a given
+// bar interval, since Yahoo truncates long intraday ranges much sooner
+// than daily ones.
+func backfillWindow(interval string) time.Duration {
+	switch interval {
+	case "1m", "2m", "5m":
+		return 24 * time.Hour
+	case "1h", "60m":
+		return 7 * 24 * time.Hour
+	default:
+		return 60 * 24 * time.Hour
+	}
+}
+
+// BackfillRequest is the body of POST /v1/data/backfill.
+type BackfillRequest struct {
+	Tickers     []string `json:"names"`
+	StartDate   string   `json:"start_date"`
+	Interval    string   `json:"interval"`
+	Provider    string   `json:"provider,omitempty"`
+	Concurrency int      `json:"concurrency,omitempty"` // parallel tickers in flight, defaults to NUM_WORKERS
+}
+
+// BackfillJob tracks the progress of one backfill run across all of its
+// tickers.
+type BackfillJob struct {
+	ID               string            `json:"id"`
+	Tickers          []string          `json:"tickers"`
+	StartDate        string            `json:"start_date"`
+	Interval         string            `json:"interval"`
+	Provider         string            `json:"provider,omitempty"`
+	Concurrency      int               `json:"concurrency,omitempty"`
+	Status           string            `json:"status"` // running, completed, error
+	PointsWritten    int               `json:"points_written"`
+	CurrentTicker    string            `json:"current_ticker,omitempty"`
+	CurrentWindowEnd time.Time         `json:"current_window_end,omitempty"`
+	TotalWindows     int               `json:"total_windows"`
+	CompletedWindows int               `json:"completed_windows"`
+	StartedAt        time.Time         `json:"started_at"`
+	FinishedAt       time.Time         `json:"finished_at,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	TickerErrors     map[string]string `json:"ticker_errors,omitempty"` // per-ticker failures; other tickers keep running
+}
+
+// BackfillManager runs and tracks backfill jobs.
+type BackfillManager struct {
+	server *Server
+
+	mu   sync.Mutex
+	jobs map[string]*BackfillJob
+}
+
+func NewBackfillManager(server *Server) *BackfillManager {
+	return &BackfillManager{
+		server: server,
+		jobs:   make(map[string]*BackfillJob),
+	}
+}
+
+// Start validates req, registers a new job, and kicks off its execution in
+// the background, returning immediately with the job's ID.
+func (b *BackfillManager) Start(req BackfillRequest) (*BackfillJob, error) {
+	if len(req.Tickers) == 0 {
+		return nil, fmt.Errorf("at least one ticker is required")
+	}
+	if req.Interval == "" {
+		req.Interval = "1d"
+	}
+	if req.Concurrency <= 0 {
+		req.Concurrency = NUM_WORKERS
+	}
+
+	job := &BackfillJob{
+		ID:          fmt.Sprintf("backfill-%d", time.Now().UnixNano()),
+		Tickers:     req.Tickers,
+		StartDate:   req.StartDate,
+		Interval:    req.Interval,
+		Provider:    req.Provider,
+		Concurrency: req.Concurrency,
+		Status:      "running",
+		StartedAt:   time.Now(),
+	}
+	for _, ticker := range req.Tickers {
+		windowStart, err := b.resumeFrom(ticker, req.Interval, req.StartDate)
+		if err == nil {
+			job.TotalWindows += windowCount(windowStart, time.Now(), backfillWindow(req.Interval))
+		}
+	}
+
+	b.mu.Lock()
+	b.jobs[job.ID] = job
+	b.mu.Unlock()
+
+	go b.run(job)
+	return job, nil
+}
+
+// Get returns a snapshot of a job's progress.
+func (b *BackfillManager) Get(id string) (*BackfillJob, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job, ok := b.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+func windowCount(start, end time.Time, window time.Duration) int {
+	if !end.After(start) || window <= 0 {
+		return 0
+	}
+	return int(end.Sub(start)/window) + 1
+}
+
+// run fans tickers out across a worker pool, same shape as runFetchCycle's
+// fetchWorker pool in main.go, so tickers backfill in parallel instead of
+// one at a time. Each ticker's windows are still fetched and persisted
+// sequentially (a later window depends on the last one's cursor), but one
+// ticker's failure only marks that ticker, letting the rest of the job
+// keep running.
+func (b *BackfillManager) run(job *BackfillJob) {
+	ctx := context.Background()
+	window := backfillWindow(job.Interval)
+
+	numWorkers := job.Concurrency
+	if numWorkers <= 0 {
+		numWorkers = NUM_WORKERS
+	}
+
+	var wg sync.WaitGroup
+	tickerJobs := make(chan string, len(job.Tickers))
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go b.backfillWorker(ctx, &wg, tickerJobs, job, window)
+	}
+	for _, ticker := range job.Tickers {
+		tickerJobs <- ticker
+	}
+	close(tickerJobs)
+	wg.Wait()
+
+	b.mu.Lock()
+	if len(job.Tickers) > 0 && len(job.TickerErrors) == len(job.Tickers) {
+		job.Status = "error"
+	} else {
+		job.Status = "completed"
+	}
+	job.FinishedAt = time.Now()
+	b.mu.Unlock()
+}
+
+// backfillWorker pulls tickers off jobs until it's closed, running each
+// one's full window loop before moving to the next.
+func (b *BackfillManager) backfillWorker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan string, job *BackfillJob, window time.Duration) {
+	defer wg.Done()
+	for ticker := range jobs {
+		b.runTicker(ctx, job, ticker, window)
+	}
+}
+
+// runTicker backfills a single ticker window-by-window, writing each
+// window's points as soon as they're fetched and persisting progress after
+// each window so an interrupted job can resume. A failure is recorded
+// against this ticker only; it doesn't affect the job's other tickers.
+func (b *BackfillManager) runTicker(ctx context.Context, job *BackfillJob, ticker string, window time.Duration) {
+	b.setCurrentTicker(job, ticker)
+
+	cursor, err := b.resumeFrom(ticker, job.Interval, job.StartDate)
+	if err != nil {
+		slog.Warn("backfill: failed to resolve resume point, starting from requested start date", "ticker", ticker, "error", err)
+	}
+
+	end := time.Now()
+	for cursor.Before(end) {
+		windowEnd := cursor.Add(window)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+
+		provider, err := b.server.Providers.Resolve(job.Provider, ticker)
+		if err != nil {
+			b.failTicker(job, ticker, fmt.Errorf("resolving provider for %s: %w", ticker, err))
+			return
+		}
+
+		points, err := provider.FetchOHLCV(ctx, ticker, cursor, windowEnd, job.Interval)
+		if err != nil {
+			b.failTicker(job, ticker, fmt.Errorf("fetching %s window %s-%s: %w", ticker, cursor, windowEnd, err))
+			return
+		}
+
+		if len(points) > 0 {
+			if err := b.server.writePoints(ctx, points); err != nil {
+				b.failTicker(job, ticker, fmt.Errorf("writing %s window %s-%s: %w", ticker, cursor, windowEnd, err))
+				return
+			}
+		}
+
+		b.persistState(ticker, job.Interval, windowEnd)
+		b.advance(job, len(points), windowEnd)
+
+		cursor = windowEnd
+	}
+}
+
+func (b *BackfillManager) setCurrentTicker(job *BackfillJob, ticker string) {
+	b.mu.Lock()
+	job.CurrentTicker = ticker
+	b.mu.Unlock()
+}
+
+func (b *BackfillManager) advance(job *BackfillJob, pointsWritten int, windowEnd time.Time) {
+	b.mu.Lock()
+	job.PointsWritten += pointsWritten
+	job.CompletedWindows++
+	job.CurrentWindowEnd = windowEnd
+	b.mu.Unlock()
+}
+
+func (b *BackfillManager) failTicker(job *BackfillJob, ticker string, err error) {
+	slog.Error("backfill: ticker failed", "job_id", job.ID, "ticker", ticker, "error", err)
+	b.mu.Lock()
+	if job.TickerErrors == nil {
+		job.TickerErrors = make(map[string]string)
+	}
+	job.TickerErrors[ticker] = err.Error()
+	b.mu.Unlock()
+}
+
+// resumeFrom returns the timestamp to resume a (ticker, interval) backfill
+// from: the last persisted window end if one exists and is after the
+// requested start date, otherwise the requested start date itself.
+func (b *BackfillManager) resumeFrom(ticker, interval, startDate string) (time.Time, error) {
+	requestedStart, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		requestedStart = time.Now().AddDate(-1, 0, 0)
+	}
+
+	if b.server.QueryAPI == nil {
+		return requestedStart, nil
+	}
+
+	query := fmt.Sprintf(`
+        from(bucket: "%s")
+          |> range(start: -5y)
+          |> filter(fn: (r) => r._measurement == "%s")
+          |> filter(fn: (r) => r.ticker == "%s" and r.interval == "%s")
+          |> last()
+    `, influxBucket, backfillStateMeasurement, ticker, interval)
+
+	result, err := b.server.QueryAPI.Query(context.Background(), query)
+	if err != nil {
+		return requestedStart, err
+	}
+
+	if result.Next() {
+		resumed := result.Record().Time()
+		if resumed.After(requestedStart) {
+			return resumed, nil
+		}
+	}
+	return requestedStart, result.Err()
+}
+
+// persistState records the end of the most recently completed window for
+// (ticker, interval) so a later run can resume from it.
+func (b *BackfillManager) persistState(ticker, interval string, windowEnd time.Time) {
+	if b.server.WriteAPI == nil {
+		return
+	}
+	point := influxdb2.NewPoint(
+		backfillStateMeasurement,
+		map[string]string{"ticker": ticker, "interval": interval},
+		map[string]interface{}{"last_window_end": windowEnd.Unix()},
+		windowEnd,
+	)
+	if err := b.server.WriteAPI.WritePoint(context.Background(), point); err != nil {
+		slog.Error("failed to persist backfill state", "ticker", ticker, "interval", interval, "error", err)
+	}
+}
+
+// --- HTTP handlers ---
+
+func (s *Server) handleStartBackfill(c *gin.Context) {
+	var req BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	job, err := s.Backfill.Start(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (s *Server) handleGetBackfill(c *gin.Context) {
+	job, ok := s.Backfill.Get(c.Param("job_id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backfill job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
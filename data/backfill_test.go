@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestBackfillManager() (*BackfillManager, *MockProvider) {
+	provider := &MockProvider{name: "mock"}
+	provider.On("Supports", mock.Anything).Return(true).Maybe()
+	provider.On("FetchOHLCV", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]*write.Point{}, nil).Maybe()
+
+	registry := NewProviderRegistry()
+	registry.Register(provider)
+
+	server := &Server{Providers: registry}
+	manager := NewBackfillManager(server)
+	server.Backfill = manager
+	return manager, provider
+}
+
+func TestBackfillWindow_SizedByInterval(t *testing.T) {
+	assert.Equal(t, 24*time.Hour, backfillWindow("1m"))
+	assert.Equal(t, 7*24*time.Hour, backfillWindow("1h"))
+	assert.Equal(t, 60*24*time.Hour, backfillWindow("1d"))
+}
+
+func TestBackfillManager_Start_RequiresTickers(t *testing.T) {
+	manager, _ := newTestBackfillManager()
+
+	_, err := manager.Start(BackfillRequest{StartDate: "2020-01-01"})
+	assert.Error(t, err)
+}
+
+func TestBackfillManager_Start_RunsToCompletion(t *testing.T) {
+	manager, _ := newTestBackfillManager()
+
+	job, err := manager.Start(BackfillRequest{
+		Tickers:   []string{"SPY"},
+		StartDate: time.Now().AddDate(0, 0, -5).Format("2006-01-02"),
+		Interval:  "1d",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "running", job.Status)
+
+	assert.Eventually(t, func() bool {
+		got, ok := manager.Get(job.ID)
+		return ok && got.Status == "completed"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestBackfillManager_Start_IsolatesPerTickerErrors(t *testing.T) {
+	provider := &MockProvider{name: "mock"}
+	provider.On("Supports", mock.Anything).Return(true).Maybe()
+	provider.On("FetchOHLCV", mock.Anything, "BAD", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, assert.AnError).Maybe()
+	provider.On("FetchOHLCV", mock.Anything, "GOOD", mock.Anything, mock.Anything, mock.Anything).
+		Return([]*write.Point{}, nil).Maybe()
+
+	registry := NewProviderRegistry()
+	registry.Register(provider)
+	server := &Server{Providers: registry}
+	manager := NewBackfillManager(server)
+	server.Backfill = manager
+
+	job, err := manager.Start(BackfillRequest{
+		Tickers:   []string{"BAD", "GOOD"},
+		StartDate: time.Now().AddDate(0, 0, -5).Format("2006-01-02"),
+		Interval:  "1d",
+	})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		got, ok := manager.Get(job.ID)
+		return ok && got.Status != "running"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	got, _ := manager.Get(job.ID)
+	assert.Equal(t, "completed", got.Status)
+	assert.Contains(t, got.TickerErrors, "BAD")
+	assert.NotContains(t, got.TickerErrors, "GOOD")
+}
+
+func TestBackfillManager_Get_UnknownJob(t *testing.T) {
+	manager, _ := newTestBackfillManager()
+
+	_, ok := manager.Get("does-not-exist")
+	assert.False(t, ok)
+}
+
+func TestHandleStartBackfill_ReturnsJobImmediately(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager, _ := newTestBackfillManager()
+	server := manager.server
+	router := gin.Default()
+	router.POST("/v1/data/backfill", server.handleStartBackfill)
+
+	body := `{"names":["SPY"],"start_date":"2020-01-01","interval":"1d"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/data/backfill", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Contains(t, rec.Body.String(), "\"status\":\"running\"")
+}
+
+func TestHandleGetBackfill_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager, _ := newTestBackfillManager()
+	server := manager.server
+	router := gin.Default()
+	router.GET("/v1/backfill/:job_id", server.handleGetBackfill)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/backfill/nope", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// --- In-process LRU cache ---
+//
+// A small byte-budgeted LRU used to avoid re-running the same Flux
+// aggregation query every time a dashboard polls the same window. Entries
+// carry both a TTL (so stale aggregates expire even under light load) and a
+// content hash used as an HTTP ETag for client-side revalidation.
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	etag      string
+	expiresAt time.Time
+	size      int
+}
+
+// LRUCache is a TTL-aware, byte-budgeted least-recently-used cache safe for
+// concurrent use.
+type LRUCache struct {
+	mu        sync.Mutex
+	maxBytes  int
+	curBytes  int
+	entries   *list.List // of *cacheEntry, front = most recently used
+	positions map[string]*list.Element
+}
+
+func NewLRUCache(maxBytes int) *LRUCache {
+	return &LRUCache{
+		maxBytes:  maxBytes,
+		entries:   list.New(),
+		positions: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value and its ETag for key, if present and not
+// expired.
+func (c *LRUCache) Get(key string) (value []byte, etag string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.positions[key]
+	if !found {
+		return nil, "", false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(el)
+		return nil, "", false
+	}
+	c.entries.MoveToFront(el)
+	return entry.value, entry.etag, true
+}
+
+// Set stores value under key with the given TTL, evicting the
+// least-recently-used entries as needed to stay within maxBytes.
+func (c *LRUCache) Set(key string, value []byte, ttl time.Duration) string {
+	sum := sha256.Sum256(value)
+	etag := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.positions[key]; found {
+		c.removeElement(el)
+	}
+
+	entry := &cacheEntry{key: key, value: value, etag: etag, expiresAt: time.Now().Add(ttl), size: len(value)}
+	el := c.entries.PushFront(entry)
+	c.positions[key] = el
+	c.curBytes += entry.size
+
+	for c.curBytes > c.maxBytes && c.entries.Len() > 0 {
+		oldest := c.entries.Back()
+		if oldest == nil {
+			break
+		}
+		c.removeElement(oldest)
+	}
+	return etag
+}
+
+// removeElement deletes el from both the list and the index. Caller must
+// hold c.mu.
+func (c *LRUCache) removeElement(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.entries.Remove(el)
+	delete(c.positions, entry.key)
+	c.curBytes -= entry.size
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache_SetAndGet(t *testing.T) {
+	cache := NewLRUCache(1024)
+	etag := cache.Set("key1", []byte("hello"), time.Minute)
+
+	value, gotEtag, ok := cache.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("hello"), value)
+	assert.Equal(t, etag, gotEtag)
+}
+
+func TestLRUCache_MissingKey(t *testing.T) {
+	cache := NewLRUCache(1024)
+	_, _, ok := cache.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestLRUCache_ExpiredEntryIsEvicted(t *testing.T) {
+	cache := NewLRUCache(1024)
+	cache.Set("key1", []byte("hello"), -time.Second) // already expired
+
+	_, _, ok := cache.Get("key1")
+	assert.False(t, ok)
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsedOverBudget(t *testing.T) {
+	cache := NewLRUCache(10) // tiny budget, each value is 5 bytes
+
+	cache.Set("key1", []byte("aaaaa"), time.Minute)
+	cache.Set("key2", []byte("bbbbb"), time.Minute)
+	// key1 is now least-recently-used; this push should evict it
+	cache.Set("key3", []byte("ccccc"), time.Minute)
+
+	_, _, ok := cache.Get("key1")
+	assert.False(t, ok)
+
+	_, _, ok = cache.Get("key2")
+	assert.True(t, ok)
+
+	_, _, ok = cache.Get("key3")
+	assert.True(t, ok)
+}
+
+func TestLRUCache_GetRefreshesRecency(t *testing.T) {
+	cache := NewLRUCache(10)
+
+	cache.Set("key1", []byte("aaaaa"), time.Minute)
+	cache.Set("key2", []byte("bbbbb"), time.Minute)
+	cache.Get("key1") // key1 is now most-recently-used, key2 is oldest
+
+	cache.Set("key3", []byte("ccccc"), time.Minute) // should evict key2, not key1
+
+	_, _, ok := cache.Get("key1")
+	assert.True(t, ok)
+
+	_, _, ok = cache.Get("key2")
+	assert.False(t, ok)
+}
@@ -0,0 +1,583 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+)
+
+// --- Derived-series (technical indicator) computation ---
+//
+// Indicators are computed from stored OHLCV and either returned directly or
+// persisted alongside it as a separate "indicator_values" measurement,
+// tagged by ticker/indicator/param so Flux queries can select a single
+// series without re-computing it.
+
+const indicatorValuesMeasurement = "indicator_values"
+
+// IndicatorRequest is the body of POST /v1/data/indicators.
+type IndicatorRequest struct {
+	Ticker     string         `json:"ticker"`
+	Days       int            `json:"days"`
+	Indicators []string       `json:"indicators"` // e.g. "sma", "ema", "rsi", "macd", "bollinger", "atr", "volatility", "log_returns"
+	Params     map[string]int `json:"params,omitempty"`
+	Persist    bool           `json:"persist,omitempty"`
+}
+
+// IndicatorPoint is a single (time, value) pair of a computed series.
+type IndicatorPoint struct {
+	Time  string  `json:"time"`
+	Value float64 `json:"value"`
+}
+
+// IndicatorSeries is one named, parameterized indicator's full output.
+type IndicatorSeries struct {
+	Indicator string           `json:"indicator"`
+	Param     int              `json:"param,omitempty"`
+	Values    []IndicatorPoint `json:"values"`
+}
+
+type IndicatorResponse struct {
+	Ticker string            `json:"ticker"`
+	Series []IndicatorSeries `json:"series"`
+}
+
+func (s *Server) handleComputeIndicators(c *gin.Context) {
+	var req IndicatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+	if len(req.Indicators) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one indicator is required"})
+		return
+	}
+	if req.Days <= 0 {
+		req.Days = 252
+	}
+
+	bars, err := s.queryOHLCV(req.Ticker, req.Days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Query failed", "details": err.Error()})
+		return
+	}
+
+	series := computeIndicators(bars, req.Indicators, req.Params)
+
+	if req.Persist {
+		if err := s.persistIndicatorSeries(req.Ticker, series); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to persist indicators", "details": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, IndicatorResponse{Ticker: req.Ticker, Series: series})
+}
+
+// IndicatorFrame is a single aligned JSON frame joining close prices and
+// indicator series on a shared time axis, ready to hand to a charting
+// library without further client-side merging.
+type IndicatorFrame struct {
+	Ticker string                `json:"ticker"`
+	Times  []string              `json:"times"`
+	Close  []float64             `json:"close"`
+	Series map[string][]*float64 `json:"series"` // keyed by "<indicator>_<param>" or "<indicator>"; null where no value yet
+}
+
+// handleQueryIndicators computes close prices plus the requested indicators
+// over the same window and joins them into a single aligned frame.
+func (s *Server) handleQueryIndicators(c *gin.Context) {
+	var req IndicatorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.Ticker == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker is required"})
+		return
+	}
+	if req.Days <= 0 {
+		req.Days = 252
+	}
+
+	bars, err := s.queryOHLCV(req.Ticker, req.Days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Query failed", "details": err.Error()})
+		return
+	}
+
+	series := computeIndicators(bars, req.Indicators, req.Params)
+
+	frame := IndicatorFrame{
+		Ticker: req.Ticker,
+		Times:  barTimes(bars),
+		Close:  closePrices(bars),
+		Series: make(map[string][]*float64, len(series)),
+	}
+	for _, s2 := range series {
+		key := s2.Indicator
+		if s2.Param > 0 {
+			key = fmt.Sprintf("%s_%d", s2.Indicator, s2.Param)
+		}
+		// encoding/json cannot encode NaN, so missing values become null.
+		aligned := alignToTimes(frame.Times, s2.Values)
+		values := make([]*float64, len(aligned))
+		for i := range aligned {
+			if !math.IsNaN(aligned[i]) {
+				values[i] = &aligned[i]
+			}
+		}
+		frame.Series[key] = values
+	}
+
+	c.JSON(http.StatusOK, frame)
+}
+
+// alignToTimes expands a sparse (NaN-trimmed) indicator series back out to
+// one value per entry in times, using NaN to mark points with no value yet.
+func alignToTimes(times []string, values []IndicatorPoint) []float64 {
+	byTime := make(map[string]float64, len(values))
+	for _, v := range values {
+		byTime[v.Time] = v.Value
+	}
+	out := make([]float64, len(times))
+	for i, t := range times {
+		if v, ok := byTime[t]; ok {
+			out[i] = v
+		} else {
+			out[i] = math.NaN()
+		}
+	}
+	return out
+}
+
+// queryOHLCV pulls the last `days` bars for ticker from the stock_prices
+// measurement, pivoted into DataPoint rows sorted oldest-first.
+func (s *Server) queryOHLCV(ticker string, days int) ([]DataPoint, error) {
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+		  |> range(start: -%dd)
+		  |> filter(fn: (r) => r._measurement == "stock_prices")
+		  |> filter(fn: (r) => r.ticker == "%s")
+		  |> pivot(rowKey:["_time"], columnKey: ["_field"], valueColumn: "_value")
+		  |> sort(columns: ["_time"], desc: false)
+	`, influxBucket, days+10, ticker)
+
+	result, err := s.QueryAPI.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+
+	var bars []DataPoint
+	for result.Next() {
+		record := result.Record()
+		bar := DataPoint{Time: record.Time().Format("2006-01-02T15:04:05Z")}
+		if v, ok := record.ValueByKey("open").(float64); ok {
+			bar.Open = v
+		}
+		if v, ok := record.ValueByKey("high").(float64); ok {
+			bar.High = v
+		}
+		if v, ok := record.ValueByKey("low").(float64); ok {
+			bar.Low = v
+		}
+		if v, ok := record.ValueByKey("close").(float64); ok {
+			bar.Close = v
+		}
+		if v, ok := record.ValueByKey("adj_close").(float64); ok {
+			bar.AdjClose = v
+		}
+		if v, ok := record.ValueByKey("volume").(int64); ok {
+			bar.Volume = v
+		}
+		bars = append(bars, bar)
+	}
+	if result.Err() != nil {
+		return nil, result.Err()
+	}
+	if len(bars) > days {
+		bars = bars[len(bars)-days:]
+	}
+	return bars, nil
+}
+
+// persistIndicatorSeries writes computed indicator values into InfluxDB,
+// tagged by ticker/indicator/param, so a nightly job can query them directly
+// instead of recomputing from OHLCV every time. Each series is appended
+// incrementally: only points after the latest already-persisted value (per
+// latestIndicatorTimestamp, indicator_values' analogue of getLatestTimestamp)
+// are written, so a recurring job only pays for the new bars each run.
+func (s *Server) persistIndicatorSeries(ticker string, series []IndicatorSeries) error {
+	for _, s2 := range series {
+		latest, hasLatest := s.latestIndicatorTimestamp(ticker, s2.Indicator, s2.Param)
+
+		for _, pt := range s2.Values {
+			t, err := time.Parse("2006-01-02T15:04:05Z", pt.Time)
+			if err != nil {
+				continue
+			}
+			if hasLatest && !t.After(latest) {
+				continue
+			}
+			point := influxdb2.NewPoint(
+				indicatorValuesMeasurement,
+				map[string]string{
+					"ticker":    ticker,
+					"indicator": s2.Indicator,
+					"param":     fmt.Sprintf("%d", s2.Param),
+				},
+				map[string]interface{}{"value": pt.Value},
+				t,
+			)
+			if err := s.WriteAPI.WritePoint(context.Background(), point); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// latestIndicatorTimestamp returns the timestamp of the most recently
+// persisted value for a ticker/indicator/param triple, mirroring
+// getLatestTimestamp's role for raw OHLCV bars.
+func (s *Server) latestIndicatorTimestamp(ticker, indicator string, param int) (time.Time, bool) {
+	if s.QueryAPI == nil {
+		return time.Time{}, false
+	}
+
+	query := fmt.Sprintf(`
+		from(bucket: "%s")
+		  |> range(start: -5y)
+		  |> filter(fn: (r) => r._measurement == "%s")
+		  |> filter(fn: (r) => r.ticker == "%s" and r.indicator == "%s" and r.param == "%d")
+		  |> last()
+	`, influxBucket, indicatorValuesMeasurement, ticker, indicator, param)
+
+	result, err := s.QueryAPI.Query(context.Background(), query)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if result.Next() {
+		return result.Record().Time(), true
+	}
+	return time.Time{}, false
+}
+
+// --- Indicator math ---
+
+func computeIndicators(bars []DataPoint, names []string, params map[string]int) []IndicatorSeries {
+	closes := closePrices(bars)
+	times := barTimes(bars)
+
+	var out []IndicatorSeries
+	for _, name := range names {
+		switch name {
+		case "sma":
+			period := paramOrDefault(params, "sma", 20)
+			out = append(out, IndicatorSeries{Indicator: "sma", Param: period, Values: toPoints(times, sma(closes, period))})
+		case "ema":
+			period := paramOrDefault(params, "ema", 20)
+			out = append(out, IndicatorSeries{Indicator: "ema", Param: period, Values: toPoints(times, ema(closes, period))})
+		case "rsi":
+			period := paramOrDefault(params, "rsi", 14)
+			out = append(out, IndicatorSeries{Indicator: "rsi", Param: period, Values: toPoints(times, rsi(closes, period))})
+		case "macd":
+			macdLine, signalLine, histogram := macd(closes, 12, 26, 9)
+			out = append(out, IndicatorSeries{Indicator: "macd", Values: toPoints(times, macdLine)})
+			out = append(out, IndicatorSeries{Indicator: "macd_signal", Values: toPoints(times, signalLine)})
+			out = append(out, IndicatorSeries{Indicator: "macd_histogram", Values: toPoints(times, histogram)})
+		case "bollinger":
+			period := paramOrDefault(params, "bollinger", 20)
+			upper, middle, lower := bollingerBands(closes, period, 2)
+			out = append(out, IndicatorSeries{Indicator: "bb_upper", Param: period, Values: toPoints(times, upper)})
+			out = append(out, IndicatorSeries{Indicator: "bb_middle", Param: period, Values: toPoints(times, middle)})
+			out = append(out, IndicatorSeries{Indicator: "bb_lower", Param: period, Values: toPoints(times, lower)})
+		case "atr":
+			period := paramOrDefault(params, "atr", 14)
+			out = append(out, IndicatorSeries{Indicator: "atr", Param: period, Values: toPoints(times, atr(bars, period))})
+		case "volatility":
+			period := paramOrDefault(params, "volatility", 21)
+			out = append(out, IndicatorSeries{Indicator: "volatility", Param: period, Values: toPoints(times, realizedVolatility(closes, period))})
+		case "log_returns":
+			out = append(out, IndicatorSeries{Indicator: "log_returns", Values: toPoints(times, logReturns(closes))})
+		}
+	}
+	return out
+}
+
+func paramOrDefault(params map[string]int, key string, def int) int {
+	if params == nil {
+		return def
+	}
+	if v, ok := params[key]; ok && v > 0 {
+		return v
+	}
+	return def
+}
+
+func closePrices(bars []DataPoint) []float64 {
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Close
+	}
+	return closes
+}
+
+func barTimes(bars []DataPoint) []string {
+	times := make([]string, len(bars))
+	for i, b := range bars {
+		times[i] = b.Time
+	}
+	return times
+}
+
+// toPoints zips parallel times/values slices into IndicatorPoints, skipping
+// any index where value is NaN (not enough history yet to compute it).
+func toPoints(times []string, values []float64) []IndicatorPoint {
+	var points []IndicatorPoint
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		points = append(points, IndicatorPoint{Time: times[i], Value: v})
+	}
+	return points
+}
+
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+func sma(closes []float64, period int) []float64 {
+	out := nanSeries(len(closes))
+	if period <= 0 {
+		return out
+	}
+	var sum float64
+	for i, c := range closes {
+		sum += c
+		if i >= period {
+			sum -= closes[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+func ema(closes []float64, period int) []float64 {
+	out := nanSeries(len(closes))
+	if period <= 0 || len(closes) == 0 {
+		return out
+	}
+	multiplier := 2.0 / float64(period+1)
+	var prev float64
+	for i, c := range closes {
+		if i == period-1 {
+			// seed with the SMA of the first `period` closes
+			var sum float64
+			for j := 0; j < period; j++ {
+				sum += closes[j]
+			}
+			prev = sum / float64(period)
+			out[i] = prev
+		} else if i >= period {
+			prev = (c-prev)*multiplier + prev
+			out[i] = prev
+		}
+	}
+	return out
+}
+
+func rsi(closes []float64, period int) []float64 {
+	out := nanSeries(len(closes))
+	if period <= 0 || len(closes) <= period {
+		return out
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		delta := closes[i] - closes[i-1]
+		if delta > 0 {
+			avgGain += delta
+		} else {
+			avgLoss -= delta
+		}
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = rsiFromAverages(avgGain, avgLoss)
+
+	for i := period + 1; i < len(closes); i++ {
+		delta := closes[i] - closes[i-1]
+		gain, loss := 0.0, 0.0
+		if delta > 0 {
+			gain = delta
+		} else {
+			loss = -delta
+		}
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = rsiFromAverages(avgGain, avgLoss)
+	}
+	return out
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - (100 / (1 + rs))
+}
+
+func macd(closes []float64, fast, slow, signalPeriod int) (macdLine, signalLine, histogram []float64) {
+	fastEMA := ema(closes, fast)
+	slowEMA := ema(closes, slow)
+
+	macdLine = nanSeries(len(closes))
+	for i := range closes {
+		if !math.IsNaN(fastEMA[i]) && !math.IsNaN(slowEMA[i]) {
+			macdLine[i] = fastEMA[i] - slowEMA[i]
+		}
+	}
+
+	signalLine = emaSkippingNaN(macdLine, signalPeriod)
+
+	histogram = nanSeries(len(closes))
+	for i := range closes {
+		if !math.IsNaN(macdLine[i]) && !math.IsNaN(signalLine[i]) {
+			histogram[i] = macdLine[i] - signalLine[i]
+		}
+	}
+	return
+}
+
+// emaSkippingNaN computes an EMA over a series that may start with leading
+// NaNs (as macdLine does), treating the first non-NaN run as the input.
+func emaSkippingNaN(values []float64, period int) []float64 {
+	out := nanSeries(len(values))
+	start := -1
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return out
+	}
+	trimmed := ema(values[start:], period)
+	copy(out[start:], trimmed)
+	return out
+}
+
+func bollingerBands(closes []float64, period int, numStdDev float64) (upper, middle, lower []float64) {
+	middle = sma(closes, period)
+	upper = nanSeries(len(closes))
+	lower = nanSeries(len(closes))
+
+	for i := range closes {
+		if math.IsNaN(middle[i]) {
+			continue
+		}
+		var sumSq float64
+		for j := i - period + 1; j <= i; j++ {
+			diff := closes[j] - middle[i]
+			sumSq += diff * diff
+		}
+		stdDev := math.Sqrt(sumSq / float64(period))
+		upper[i] = middle[i] + numStdDev*stdDev
+		lower[i] = middle[i] - numStdDev*stdDev
+	}
+	return
+}
+
+func atr(bars []DataPoint, period int) []float64 {
+	out := nanSeries(len(bars))
+	if period <= 0 || len(bars) == 0 {
+		return out
+	}
+
+	trueRanges := make([]float64, len(bars))
+	for i, b := range bars {
+		if i == 0 {
+			trueRanges[i] = b.High - b.Low
+			continue
+		}
+		prevClose := bars[i-1].Close
+		trueRanges[i] = math.Max(b.High-b.Low, math.Max(math.Abs(b.High-prevClose), math.Abs(b.Low-prevClose)))
+	}
+
+	var sum float64
+	for i, tr := range trueRanges {
+		sum += tr
+		if i >= period {
+			sum -= trueRanges[i-period]
+		}
+		if i >= period-1 {
+			out[i] = sum / float64(period)
+		}
+	}
+	return out
+}
+
+func logReturns(closes []float64) []float64 {
+	out := nanSeries(len(closes))
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] != 0 {
+			out[i] = math.Log(closes[i] / closes[i-1])
+		}
+	}
+	return out
+}
+
+// realizedVolatility is the rolling stddev of log-returns, annualized
+// assuming daily bars (252 trading days/year).
+func realizedVolatility(closes []float64, period int) []float64 {
+	returns := logReturns(closes)
+	out := nanSeries(len(closes))
+	if period <= 0 {
+		return out
+	}
+
+	for i := range closes {
+		if i < period {
+			continue
+		}
+		var sum, sumSq float64
+		count := 0
+		for j := i - period + 1; j <= i; j++ {
+			if math.IsNaN(returns[j]) {
+				continue
+			}
+			sum += returns[j]
+			sumSq += returns[j] * returns[j]
+			count++
+		}
+		if count < 2 {
+			continue
+		}
+		mean := sum / float64(count)
+		variance := sumSq/float64(count) - mean*mean
+		if variance < 0 {
+			variance = 0
+		}
+		out[i] = math.Sqrt(variance) * math.Sqrt(252)
+	}
+	return out
+}
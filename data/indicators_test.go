@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func syntheticBars(closes []float64) []DataPoint {
+	bars := make([]DataPoint, len(closes))
+	for i, c := range closes {
+		bars[i] = DataPoint{
+			Time:  fmt.Sprintf("2020-01-%02dT00:00:00Z", i%28+1),
+			Open:  c,
+			High:  c + 1,
+			Low:   c - 1,
+			Close: c,
+		}
+	}
+	return bars
+}
+
+func TestSMA_InsufficientHistoryIsNaN(t *testing.T) {
+	closes := []float64{1, 2, 3}
+	out := sma(closes, 5)
+	for _, v := range out {
+		assert.True(t, math.IsNaN(v))
+	}
+}
+
+func TestSMA_KnownValues(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	out := sma(closes, 3)
+	assert.True(t, math.IsNaN(out[0]))
+	assert.True(t, math.IsNaN(out[1]))
+	assert.InDelta(t, 2.0, out[2], 1e-9) // (1+2+3)/3
+	assert.InDelta(t, 3.0, out[3], 1e-9) // (2+3+4)/3
+	assert.InDelta(t, 4.0, out[4], 1e-9) // (3+4+5)/3
+}
+
+func TestEMA_SeedsWithSMA(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	out := ema(closes, 3)
+	assert.True(t, math.IsNaN(out[0]))
+	assert.True(t, math.IsNaN(out[1]))
+	assert.InDelta(t, 2.0, out[2], 1e-9) // seeded with SMA(1,2,3)
+	assert.False(t, math.IsNaN(out[3]))
+}
+
+func TestRSI_AllGainsIsHundred(t *testing.T) {
+	closes := make([]float64, 20)
+	for i := range closes {
+		closes[i] = float64(i + 1)
+	}
+	out := rsi(closes, 14)
+	assert.InDelta(t, 100.0, out[len(out)-1], 1e-9)
+}
+
+func TestMACD_HistogramIsDifferenceOfLines(t *testing.T) {
+	closes := make([]float64, 60)
+	for i := range closes {
+		closes[i] = 100 + float64(i)*0.5
+	}
+	macdLine, signalLine, histogram := macd(closes, 12, 26, 9)
+	for i := range closes {
+		if math.IsNaN(macdLine[i]) || math.IsNaN(signalLine[i]) {
+			continue
+		}
+		assert.InDelta(t, macdLine[i]-signalLine[i], histogram[i], 1e-9)
+	}
+}
+
+func TestBollingerBands_MiddleIsSMA(t *testing.T) {
+	closes := []float64{10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+	upper, middle, lower := bollingerBands(closes, 5, 2)
+	expectedMiddle := sma(closes, 5)
+	for i := range closes {
+		if math.IsNaN(expectedMiddle[i]) {
+			continue
+		}
+		assert.InDelta(t, expectedMiddle[i], middle[i], 1e-9)
+		assert.True(t, upper[i] >= middle[i])
+		assert.True(t, lower[i] <= middle[i])
+	}
+}
+
+func TestATR_FirstBarIsHighMinusLow(t *testing.T) {
+	bars := syntheticBars([]float64{100, 101, 102})
+	out := atr(bars, 1)
+	assert.InDelta(t, 2.0, out[0], 1e-9) // high-low == (c+1)-(c-1) == 2
+}
+
+func TestLogReturns_FirstIsNaN(t *testing.T) {
+	closes := []float64{100, 110, 99}
+	out := logReturns(closes)
+	assert.True(t, math.IsNaN(out[0]))
+	assert.InDelta(t, math.Log(110.0/100.0), out[1], 1e-9)
+}
+
+func TestRealizedVolatility_ConstantPricesIsZero(t *testing.T) {
+	closes := make([]float64, 30)
+	for i := range closes {
+		closes[i] = 100
+	}
+	out := realizedVolatility(closes, 21)
+	for _, v := range out {
+		if !math.IsNaN(v) {
+			assert.InDelta(t, 0.0, v, 1e-9)
+		}
+	}
+}
+
+func TestComputeIndicators_UnknownNameIsIgnored(t *testing.T) {
+	bars := syntheticBars([]float64{1, 2, 3, 4, 5})
+	series := computeIndicators(bars, []string{"not-a-real-indicator"}, nil)
+	assert.Len(t, series, 0)
+}
+
+func TestAlignToTimes_FillsMissingWithNaN(t *testing.T) {
+	times := []string{"t1", "t2", "t3"}
+	values := []IndicatorPoint{{Time: "t2", Value: 5.0}}
+
+	out := alignToTimes(times, values)
+
+	assert.True(t, math.IsNaN(out[0]))
+	assert.InDelta(t, 5.0, out[1], 1e-9)
+	assert.True(t, math.IsNaN(out[2]))
+}
+
+func TestLatestIndicatorTimestamp_NilQueryAPIHasNoLatest(t *testing.T) {
+	server := &Server{}
+
+	_, ok := server.latestIndicatorTimestamp("SPY", "sma", 20)
+	assert.False(t, ok)
+}
+
+func TestPersistIndicatorSeries_NilQueryAPIPersistsEveryPoint(t *testing.T) {
+	mockWriteAPI := new(MockWriteAPIBlocking)
+	mockWriteAPI.On("WritePoint", mock.Anything, mock.Anything).Return(nil)
+	server := &Server{WriteAPI: mockWriteAPI}
+
+	series := []IndicatorSeries{{
+		Indicator: "sma",
+		Param:     20,
+		Values: []IndicatorPoint{
+			{Time: "2020-01-01T00:00:00Z", Value: 1.0},
+			{Time: "2020-01-02T00:00:00Z", Value: 2.0},
+		},
+	}}
+
+	err := server.persistIndicatorSeries("SPY", series)
+	assert.NoError(t, err)
+	mockWriteAPI.AssertNumberOfCalls(t, "WritePoint", 2)
+}
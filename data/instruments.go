@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// --- Instrument metadata catalog ---
+//
+// A small in-memory catalog describing per-symbol trading conventions.
+// Every Set is also written to the InfluxDB "instruments" measurement; the
+// catalog is not reloaded from it at startup, so it starts empty after a
+// restart. Server.writePoints (the fetch and backfill paths) and the Alpaca
+// stream's batch writer consult it to quantize prices/volumes to the
+// instrument's tick size and to tag points with their asset class before
+// they're written; ticks persisted by StreamManager are not quantized.
+
+const instrumentsMeasurement = "instruments"
+
+// InstrumentMetadata describes the trading conventions for a single symbol.
+type InstrumentMetadata struct {
+	Ticker         string    `json:"ticker"`
+	AssetClass     string    `json:"asset_class"` // equity, crypto, futures
+	QuoteCurrency  string    `json:"quote_currency,omitempty"`
+	PriceTickSize  float64   `json:"price_tick_size,omitempty"`
+	AmountTickSize float64   `json:"amount_tick_size,omitempty"`
+	ContractValue  float64   `json:"contract_value,omitempty"`
+	DeliveryDate   time.Time `json:"delivery_date,omitempty"`
+	ContractType   string    `json:"contract_type,omitempty"` // this_week, next_week, quarter
+}
+
+// InstrumentCatalog holds every registered instrument's metadata, keyed by
+// ticker, guarded by a mutex for concurrent provider/stream access.
+type InstrumentCatalog struct {
+	writeAPI api.WriteAPIBlocking
+
+	mu   sync.RWMutex
+	byID map[string]InstrumentMetadata
+}
+
+func NewInstrumentCatalog(writeAPI api.WriteAPIBlocking) *InstrumentCatalog {
+	return &InstrumentCatalog{
+		writeAPI: writeAPI,
+		byID:     make(map[string]InstrumentMetadata),
+	}
+}
+
+// Set registers or replaces metadata for a ticker and persists it.
+func (c *InstrumentCatalog) Set(meta InstrumentMetadata) error {
+	if meta.Ticker == "" {
+		return fmt.Errorf("ticker is required")
+	}
+	if meta.AssetClass == "" {
+		return fmt.Errorf("asset_class is required")
+	}
+
+	c.mu.Lock()
+	c.byID[meta.Ticker] = meta
+	c.mu.Unlock()
+
+	c.persist(meta)
+	return nil
+}
+
+// Get returns the metadata registered for a ticker, if any.
+func (c *InstrumentCatalog) Get(ticker string) (InstrumentMetadata, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	meta, ok := c.byID[ticker]
+	return meta, ok
+}
+
+// List returns every registered instrument, optionally filtered by asset
+// class.
+func (c *InstrumentCatalog) List(assetClass string) []InstrumentMetadata {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]InstrumentMetadata, 0, len(c.byID))
+	for _, meta := range c.byID {
+		if assetClass != "" && meta.AssetClass != assetClass {
+			continue
+		}
+		out = append(out, meta)
+	}
+	return out
+}
+
+func (c *InstrumentCatalog) persist(meta InstrumentMetadata) {
+	if c.writeAPI == nil {
+		return
+	}
+	point := influxdb2.NewPoint(
+		instrumentsMeasurement,
+		map[string]string{"ticker": meta.Ticker},
+		map[string]interface{}{
+			"asset_class":      meta.AssetClass,
+			"quote_currency":   meta.QuoteCurrency,
+			"price_tick_size":  meta.PriceTickSize,
+			"amount_tick_size": meta.AmountTickSize,
+			"contract_value":   meta.ContractValue,
+			"contract_type":    meta.ContractType,
+		},
+		time.Now(),
+	)
+	if err := c.writeAPI.WritePoint(context.Background(), point); err != nil {
+		slog.Error("failed to persist instrument metadata", "ticker", meta.Ticker, "error", err)
+	}
+}
+
+// roundToTick rounds value to the nearest multiple of tick. A non-positive
+// tick leaves the value untouched, since it means no quantization was
+// configured for the instrument.
+func roundToTick(value, tick float64) float64 {
+	if tick <= 0 {
+		return value
+	}
+	return tick * float64(int64(value/tick+0.5))
+}
+
+// quantizePoints rounds each point's price/volume fields to its
+// instrument's tick size and tags it with the instrument's asset class.
+// Points for tickers that aren't in the catalog are left untouched.
+func quantizePoints(catalog *InstrumentCatalog, points []*write.Point) {
+	if catalog == nil {
+		return
+	}
+	for _, p := range points {
+		var ticker string
+		for _, tag := range p.TagList() {
+			if tag.Key == "ticker" {
+				ticker = tag.Value
+				break
+			}
+		}
+		meta, ok := catalog.Get(ticker)
+		if !ok {
+			continue
+		}
+
+		for _, field := range p.FieldList() {
+			switch field.Key {
+			case "open", "high", "low", "close", "adj_close", "price", "bid_price", "ask_price":
+				if v, ok := field.Value.(float64); ok {
+					field.Value = roundToTick(v, meta.PriceTickSize)
+				}
+			case "volume", "size":
+				switch v := field.Value.(type) {
+				case float64:
+					field.Value = roundToTick(v, meta.AmountTickSize)
+				case int64:
+					field.Value = int64(roundToTick(float64(v), meta.AmountTickSize))
+				}
+			}
+		}
+
+		hasAssetClassTag := false
+		for _, tag := range p.TagList() {
+			if tag.Key == "asset_class" {
+				hasAssetClassTag = true
+				break
+			}
+		}
+		if !hasAssetClassTag {
+			p.AddTag("asset_class", meta.AssetClass)
+		}
+	}
+}
+
+// --- HTTP handlers ---
+
+func (s *Server) handlePutInstrument(c *gin.Context) {
+	var meta InstrumentMetadata
+	if err := c.ShouldBindJSON(&meta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	meta.Ticker = c.Param("ticker")
+
+	if err := s.Instruments.Set(meta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, meta)
+}
+
+func (s *Server) handleGetInstrument(c *gin.Context) {
+	meta, ok := s.Instruments.Get(c.Param("ticker"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instrument not found"})
+		return
+	}
+	c.JSON(http.StatusOK, meta)
+}
+
+func (s *Server) handleListInstruments(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"instruments": s.Instruments.List(c.Query("asset_class"))})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentCatalog_SetAndGet(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+
+	err := catalog.Set(InstrumentMetadata{Ticker: "SPY", AssetClass: "equity", PriceTickSize: 0.01})
+	assert.NoError(t, err)
+
+	meta, ok := catalog.Get("SPY")
+	assert.True(t, ok)
+	assert.Equal(t, "equity", meta.AssetClass)
+}
+
+func TestInstrumentCatalog_Set_RequiresTickerAndAssetClass(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+
+	assert.Error(t, catalog.Set(InstrumentMetadata{AssetClass: "equity"}))
+	assert.Error(t, catalog.Set(InstrumentMetadata{Ticker: "SPY"}))
+}
+
+func TestInstrumentCatalog_ListFiltersByAssetClass(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+	catalog.Set(InstrumentMetadata{Ticker: "SPY", AssetClass: "equity"})
+	catalog.Set(InstrumentMetadata{Ticker: "BTC-USD", AssetClass: "crypto"})
+
+	equities := catalog.List("equity")
+	assert.Len(t, equities, 1)
+	assert.Equal(t, "SPY", equities[0].Ticker)
+
+	assert.Len(t, catalog.List(""), 2)
+}
+
+func TestRoundToTick(t *testing.T) {
+	assert.Equal(t, 100.25, roundToTick(100.253, 0.25))
+	assert.Equal(t, 99.999, roundToTick(99.999, 0)) // non-positive tick leaves the value untouched
+}
+
+func TestQuantizePoints_RoundsKnownFieldsAndTagsAssetClass(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+	catalog.Set(InstrumentMetadata{Ticker: "BTC-USD", AssetClass: "crypto", PriceTickSize: 0.5, AmountTickSize: 1})
+
+	point := influxdb2.NewPoint(
+		"stock_prices",
+		map[string]string{"ticker": "BTC-USD"},
+		map[string]interface{}{"close": 100.24, "volume": 10.6},
+		time.Now(),
+	)
+
+	quantizePoints(catalog, []*write.Point{point})
+
+	fields := map[string]interface{}{}
+	for _, f := range point.FieldList() {
+		fields[f.Key] = f.Value
+	}
+	assert.Equal(t, 100.0, fields["close"])
+	assert.Equal(t, 11.0, fields["volume"])
+
+	tags := map[string]string{}
+	for _, tg := range point.TagList() {
+		tags[tg.Key] = tg.Value
+	}
+	assert.Equal(t, "crypto", tags["asset_class"])
+}
+
+func TestQuantizePoints_RoundsInt64Volume(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+	catalog.Set(InstrumentMetadata{Ticker: "SPY", AssetClass: "equity", PriceTickSize: 0.01, AmountTickSize: 10})
+
+	point := influxdb2.NewPoint(
+		"stock_prices",
+		map[string]string{"ticker": "SPY"},
+		map[string]interface{}{"close": 100.24, "volume": int64(1234)},
+		time.Now(),
+	)
+
+	quantizePoints(catalog, []*write.Point{point})
+
+	fields := map[string]interface{}{}
+	for _, f := range point.FieldList() {
+		fields[f.Key] = f.Value
+	}
+	assert.Equal(t, int64(1230), fields["volume"])
+}
+
+func TestQuantizePoints_UnknownTickerLeftUntouched(t *testing.T) {
+	catalog := NewInstrumentCatalog(nil)
+	point := influxdb2.NewPoint(
+		"stock_prices",
+		map[string]string{"ticker": "UNKNOWN"},
+		map[string]interface{}{"close": 100.24},
+		time.Now(),
+	)
+
+	quantizePoints(catalog, []*write.Point{point})
+
+	for _, f := range point.FieldList() {
+		if f.Key == "close" {
+			assert.Equal(t, 100.24, f.Value)
+		}
+	}
+}
+
+func TestHandlePutAndGetInstrument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{Instruments: NewInstrumentCatalog(nil)}
+	router := gin.Default()
+	router.PUT("/v1/instruments/:ticker", server.handlePutInstrument)
+	router.GET("/v1/instruments/:ticker", server.handleGetInstrument)
+
+	putReq := httptest.NewRequest(http.MethodPut, "/v1/instruments/SPY", strings.NewReader(`{"asset_class":"equity","price_tick_size":0.01}`))
+	putReq.Header.Set("Content-Type", "application/json")
+	putRec := httptest.NewRecorder()
+	router.ServeHTTP(putRec, putReq)
+	assert.Equal(t, http.StatusOK, putRec.Code)
+
+	getReq := httptest.NewRequest(http.MethodGet, "/v1/instruments/SPY", nil)
+	getRec := httptest.NewRecorder()
+	router.ServeHTTP(getRec, getReq)
+	assert.Equal(t, http.StatusOK, getRec.Code)
+	assert.Contains(t, getRec.Body.String(), "equity")
+}
+
+func TestHandleGetInstrument_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{Instruments: NewInstrumentCatalog(nil)}
+	router := gin.Default()
+	router.GET("/v1/instruments/:ticker", server.handleGetInstrument)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/instruments/NOPE", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -2,12 +2,10 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +13,7 @@ import (
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Ticker definitions (REMOVED, will come from API request)
@@ -32,9 +31,16 @@ type HTTPClient interface {
 
 // --- Server struct holds all dependencies ---
 type Server struct {
-	WriteAPI   api.WriteAPIBlocking
-	QueryAPI   api.QueryAPI
-	HTTPClient HTTPClient
+	WriteAPI       api.WriteAPIBlocking
+	QueryAPI       api.QueryAPI
+	HTTPClient     HTTPClient
+	Providers      *ProviderRegistry
+	Streams        *StreamManager
+	Scheduler      *Scheduler
+	AggregateCache *LRUCache
+	AlpacaStream   *AlpacaStreamClient
+	Instruments    *InstrumentCatalog
+	Backfill       *BackfillManager
 }
 
 // --- Yahoo Finance Structs (Unchanged) ---
@@ -71,6 +77,7 @@ type DataFetchRequest struct {
 	Tickers   []string `json:"names"`
 	StartDate string   `json:"start_date"` // e.g., "2020-01-01"
 	Interval  string   `json:"interval"`   // e.g., "1d", "1h", "1m"
+	Provider  string   `json:"provider,omitempty"` // e.g. "yahoo", "binance"; empty selects the best fallback chain per ticker
 }
 
 type DataFetchResponse struct {
@@ -94,6 +101,17 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 	slog.SetDefault(logger)
 
+	shutdownTracing, err := initTracing()
+	if err != nil {
+		slog.Error("Failed to initialize tracing.", "error", err)
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Failed to shut down tracing.", "error", err)
+		}
+	}()
+
 	if influxURL == "" || influxToken == "" || influxOrg == "" || influxBucket == "" {
 		slog.Error("InfluxDB environment variables not set. Exiting.")
 		return
@@ -103,7 +121,6 @@ func main() {
 	influxClient = influxdb2.NewClient(influxURL, influxToken)
 	// Check if the connection is valid
 	var influxReady bool
-	var err error
 
 	slog.Info("Waiting for InfluxDB to be ready...")
 	// Try to connect 10 times over 30 seconds
@@ -134,27 +151,79 @@ func main() {
 
 	slog.Info("Successfully connected to InfluxDB.")
 
+	httpClient := &http.Client{Timeout: 10 * time.Second} // Real HTTP client
+
+	registry := NewProviderRegistry()
+	registry.Register(NewYahooProvider(httpClient))
+	registry.Register(NewBinanceProvider(httpClient))
+	registry.Register(NewAlphaVantageProvider(httpClient))
+	registry.Register(NewIEXProvider(httpClient))
+	registry.Register(NewAlpacaProvider(httpClient))
+
+	writeAPI := influxClient.WriteAPIBlocking(influxOrg, influxBucket)
+
+	// Live ticks are served by polling the fallback provider chain until a
+	// native streaming provider (Binance/Polygon WS) is registered.
+	tickSource := NewPollingTickSource(registry, 5*time.Second)
+
 	// Create Server instance with all dependencies
 	server := &Server{
-		WriteAPI:   influxClient.WriteAPIBlocking(influxOrg, influxBucket),
-		QueryAPI:   influxClient.QueryAPI(influxOrg),
-		HTTPClient: &http.Client{Timeout: 10 * time.Second}, // Real HTTP client
+		WriteAPI:       writeAPI,
+		QueryAPI:       influxClient.QueryAPI(influxOrg),
+		HTTPClient:     httpClient,
+		Providers:      registry,
+		Streams:        NewStreamManager(tickSource, writeAPI),
+		AggregateCache: NewLRUCache(aggregateCacheMaxBytes),
+		AlpacaStream:   NewAlpacaStreamClient(writeAPI),
+		Instruments:    NewInstrumentCatalog(writeAPI),
+	}
+	server.Scheduler = NewScheduler(server, writeAPI)
+	if err := server.Scheduler.LoadPersistedJobs(context.Background()); err != nil {
+		slog.Error("failed to reload persisted scheduler jobs", "error", err)
 	}
+	server.Backfill = NewBackfillManager(server)
+	server.AlpacaStream.Instruments = server.Instruments
+
+	streamCtx, stopStream := context.WithCancel(context.Background())
+	server.AlpacaStream.Start(streamCtx)
 
 	// --- NEW: Start Gin Server ---
 	router := gin.Default()
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Data endpoints - use server methods
 	router.POST("/v1/data/fetch", server.handleFetchData)
 	router.POST("/v1/data/query", server.handleQueryData)
+	router.GET("/v1/data/stream", server.handleStream)
+	router.POST("/v1/jobs", server.handleCreateJob)
+	router.GET("/v1/jobs/:id", server.handleGetJob)
+	router.DELETE("/v1/jobs/:id", server.handleDeleteJob)
+	router.GET("/v1/jobs/:id/runs", server.handleGetJobRuns)
+	router.POST("/v1/schedules", server.handleCreateJob)
+	router.GET("/v1/schedules", server.handleListSchedules)
+	router.DELETE("/v1/schedules/:name", server.handleDeleteSchedule)
+	router.POST("/v1/schedules/:name/run", server.handleRunSchedule)
+	router.POST("/v1/data/indicators", server.handleComputeIndicators)
+	router.POST("/v1/data/indicators/query", server.handleQueryIndicators)
+	router.POST("/v1/data/aggregate", server.handleAggregateData)
+	router.POST("/v1/stream/subscribe", server.handleStreamSubscribe)
+	router.POST("/v1/stream/unsubscribe", server.handleStreamUnsubscribe)
+	router.GET("/v1/stream/status", server.handleStreamStatus)
+	router.PUT("/v1/instruments/:ticker", server.handlePutInstrument)
+	router.GET("/v1/instruments/:ticker", server.handleGetInstrument)
+	router.GET("/v1/instruments", server.handleListInstruments)
+	router.POST("/v1/data/backfill", server.handleStartBackfill)
+	router.GET("/v1/backfill/:job_id", server.handleGetBackfill)
 
 	slog.Info("Starting finance-data API server on :8000")
 	if err := router.Run(":8000"); err != nil {
 		slog.Error("Gin server failed", "error", err)
 	}
+	server.AlpacaStream.Stop()
+	stopStream()
 	defer influxClient.Close()
 }
 
@@ -177,83 +246,141 @@ func (s *Server) handleFetchData(c *gin.Context) {
 
 	slog.Info("Handling data fetch request", "tickers", req.Tickers, "interval", req.Interval)
 
+	ctx, span := tracer.Start(c.Request.Context(), "handle_fetch_data")
+	defer span.End()
+
+	finalDetails := s.runFetchCycle(ctx, req.Tickers, req.StartDate, req.Interval, req.Provider, NUM_WORKERS)
+
+	c.JSON(http.StatusOK, DataFetchResponse{
+		Status:  "success",
+		Message: fmt.Sprintf("Data fetch cycle completed for %d tickers", len(req.Tickers)),
+		Details: finalDetails,
+	})
+}
+
+// runFetchCycle fetches every ticker in tickers through a pool of workers and
+// returns the per-ticker result details. It's shared by the on-demand
+// handleFetchData handler and the Scheduler's recurring jobs.
+func (s *Server) runFetchCycle(ctx context.Context, tickers []string, startDate, interval, provider string, numWorkers int) map[string]string {
 	var wg sync.WaitGroup
-	tickerJobs := make(chan string, len(req.Tickers))
-	results := make(chan map[string]string, len(req.Tickers))
+	tickerJobs := make(chan string, len(tickers))
+	results := make(chan map[string]string, len(tickers))
 
-	// Create worker goroutines
-	for i := 0; i < NUM_WORKERS; i++ {
+	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		// Give each worker access to the server's dependencies
-		go s.fetchWorker(i, &wg, tickerJobs, results, req.StartDate, req.Interval)
+		go s.fetchWorker(ctx, i, &wg, tickerJobs, results, startDate, interval, provider)
 	}
 
-	// Send jobs
-	for _, ticker := range req.Tickers {
+	for _, ticker := range tickers {
 		tickerJobs <- ticker
 	}
 	close(tickerJobs)
 
-	// Wait for all workers to finish
 	wg.Wait()
 	close(results)
 
-	// Collect results
 	finalDetails := make(map[string]string)
 	for res := range results {
 		for k, v := range res {
 			finalDetails[k] = v
 		}
 	}
-
-	c.JSON(http.StatusOK, DataFetchResponse{
-		Status:  "success",
-		Message: fmt.Sprintf("Data fetch cycle completed for %d tickers", len(req.Tickers)),
-		Details: finalDetails,
-	})
+	return finalDetails
 }
 
 // fetchWorker processes a single ticker
-func (s *Server) fetchWorker(id int, wg *sync.WaitGroup,
+func (s *Server) fetchWorker(ctx context.Context, id int, wg *sync.WaitGroup,
 	jobs <-chan string, results chan<- map[string]string,
-	startDate string, interval string) {
+	startDate string, interval string, provider string) {
 
 	defer wg.Done()
 	for ticker := range jobs {
 		slog.Info("Worker processing", "worker_id", id, "ticker", ticker)
 
-		// 1. Find latest timestamp
-		latestTime, err := s.getLatestTimestamp(ticker, startDate)
-		if err != nil {
-			slog.Error("Failed to get latest timestamp", "worker_id", id, "ticker", ticker, "error", err)
-			results <- map[string]string{ticker: "Error: " + err.Error()}
-			continue
-		}
+		fetchJobsInFlight.Inc()
+		result := s.fetchTicker(ctx, id, ticker, startDate, interval, provider)
+		fetchJobsInFlight.Dec()
 
-		// 2. Fetch data from Yahoo
-		points, err := s.fetchYahooData(ticker, latestTime, interval)
-		if err != nil {
-			slog.Error("Failed to fetch Yahoo data", "worker_id", id, "ticker", ticker, "error", err)
-			results <- map[string]string{ticker: "Error: " + err.Error()}
-			continue
-		}
+		results <- result
+	}
+}
+
+// fetchTicker runs the fetch-timestamp/provider-fetch/write sequence for a
+// single ticker, recording metrics and a trace span covering the whole
+// sequence.
+func (s *Server) fetchTicker(ctx context.Context, workerID int, ticker, startDate, interval, provider string) map[string]string {
+	spanCtx, span := startSpan(ctx, "fetch_ticker", ticker)
+	defer span.End()
+
+	start := time.Now()
+	providerLabel := provider
+	if providerLabel == "" {
+		providerLabel = "auto"
+	}
+	result := "success"
+	defer func() {
+		fetchDurationSeconds.WithLabelValues(ticker, providerLabel).Observe(time.Since(start).Seconds())
+		fetchRequestsTotal.WithLabelValues(ticker, providerLabel, result).Inc()
+	}()
+
+	// 1. Find latest timestamp
+	latestTime, err := s.getLatestTimestamp(spanCtx, ticker, startDate)
+	if err != nil {
+		slog.Error("Failed to get latest timestamp", "worker_id", workerID, "ticker", ticker, "error", err)
+		result = "error"
+		return map[string]string{ticker: "Error: " + err.Error()}
+	}
 
-		// 3. Write to Influx
-		if len(points) > 0 {
-			if err := s.WriteAPI.WritePoint(context.Background(), points...); err != nil {
-				slog.Error("Failed to write to InfluxDB", "worker_id", id, "ticker", ticker, "error", err)
-				results <- map[string]string{ticker: "Error: " + err.Error()}
-			}
-			results <- map[string]string{ticker: fmt.Sprintf("%d points written", len(points))}
-		} else {
-			slog.Info("No new data to write", "worker_id", id, "ticker", ticker)
-			results <- map[string]string{ticker: "No new data"}
+	// 2. Resolve and fetch data from the configured provider (or fallback chain)
+	dataProvider, err := s.Providers.Resolve(provider, ticker)
+	if err != nil {
+		slog.Error("Failed to resolve market data provider", "worker_id", workerID, "ticker", ticker, "error", err)
+		result = "error"
+		return map[string]string{ticker: "Error: " + err.Error()}
+	}
+	providerLabel = dataProvider.Name()
+
+	points, err := dataProvider.FetchOHLCV(spanCtx, ticker, latestTime, time.Now(), interval)
+	if err != nil {
+		slog.Error("Failed to fetch market data", "worker_id", workerID, "ticker", ticker, "provider", dataProvider.Name(), "error", err)
+		result = "error"
+		return map[string]string{ticker: "Error: " + err.Error()}
+	}
+
+	// 3. Write to Influx
+	if len(points) > 0 {
+		if err := s.writePoints(spanCtx, points); err != nil {
+			slog.Error("Failed to write to InfluxDB", "worker_id", workerID, "ticker", ticker, "error", err)
+			result = "error"
+			return map[string]string{ticker: "Error: " + err.Error()}
 		}
+		return map[string]string{ticker: fmt.Sprintf("%d points written", len(points))}
+	}
+
+	slog.Info("No new data to write", "worker_id", workerID, "ticker", ticker)
+	return map[string]string{ticker: "No new data"}
+}
+
+// writePoints wraps WriteAPI.WritePoint with a trace span and the
+// influx_write_duration_seconds histogram.
+func (s *Server) writePoints(ctx context.Context, points []*write.Point) error {
+	_, span := tracer.Start(ctx, "influx_write")
+	defer span.End()
+
+	quantizePoints(s.Instruments, points)
+
+	start := time.Now()
+	err := s.WriteAPI.WritePoint(ctx, points...)
+	result := "success"
+	if err != nil {
+		result = "error"
 	}
+	influxWriteDurationSeconds.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	return err
 }
 
 // getLatestTimestamp modified to use a default start date if needed
-func (s *Server) getLatestTimestamp(ticker string, defaultStartDate string) (time.Time, error) {
+func (s *Server) getLatestTimestamp(ctx context.Context, ticker string, defaultStartDate string) (time.Time, error) {
 	// Parse the provided start date
 	defaultStartTime, err := time.Parse("2006-01-02", defaultStartDate)
 	if err != nil {
@@ -264,6 +391,10 @@ func (s *Server) getLatestTimestamp(ticker string, defaultStartDate string) (tim
         }
 	}
 
+	if s.QueryAPI == nil {
+		return defaultStartTime, nil
+	}
+
 	query := fmt.Sprintf(`
         from(bucket: "%s")
           |> range(start: -30d) // Only check recent history
@@ -272,7 +403,7 @@ func (s *Server) getLatestTimestamp(ticker string, defaultStartDate string) (tim
           |> last()
     `, influxBucket, ticker)
 
-	result, err := s.QueryAPI.Query(context.Background(), query)
+	result, err := s.QueryAPI.Query(ctx, query)
 	if err != nil {
 		return defaultStartTime, err
 	}
@@ -292,92 +423,6 @@ func (s *Server) getLatestTimestamp(ticker string, defaultStartDate string) (tim
 	return defaultStartTime, nil
 }
 
-// fetchYahooData modified to accept an interval and use injected HTTP client
-func (s *Server) fetchYahooData(ticker string, startTime time.Time, interval string) ([]*write.Point, error) {
-	start := startTime.Unix()
-	end := time.Now().Unix()
-
-	if start > end {
-		return nil, nil // Start time is in the future, no data to fetch
-	}
-
-	// --- MODIFIED URL ---
-	url := fmt.Sprintf(
-		"https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=%s&events=history",
-		ticker, start, end, interval,
-	)
-
-	// Use the injected HTTP client instead of creating a new one
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create the http request %w", err)
-	}
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
-
-	resp, err := s.HTTPClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Yahoo API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Yahoo API returned status %s", resp.Status)
-	}
-
-	var chartData YahooChartResponse
-	if err := json.NewDecoder(resp.Body).Decode(&chartData); err != nil {
-		return nil, fmt.Errorf("failed to decode Yahoo JSON: %w", err)
-	}
-
-	if chartData.Chart.Error != nil {
-		return nil, fmt.Errorf("Yahoo API error: %v", chartData.Chart.Error)
-	}
-
-	if len(chartData.Chart.Result) == 0 {
-		return nil, fmt.Errorf("no results in Yahoo response for ticker %s", ticker)
-	}
-
-	var points []*write.Point
-	res := chartData.Chart.Result[0]
-
-	if len(res.Indicators.AdjClose) == 0 || len(res.Indicators.Quote) == 0 {
-		return nil, fmt.Errorf("incomplete indicators in Yahoo response for ticker %s", ticker)
-	}
-
-	adjCloseData := res.Indicators.AdjClose[0].AdjClose
-	quoteData := res.Indicators.Quote[0]
-
-	for i, ts := range res.Timestamp {
-		if len(adjCloseData) <= i ||
-			len(quoteData.Close) <= i ||
-			len(quoteData.Open) <= i ||
-			len(quoteData.High) <= i ||
-			len(quoteData.Low) <= i ||
-			len(quoteData.Volume) <= i {
-			slog.Warn("Skipping incomplete data point", "ticker", ticker, "timestamp", ts)
-			continue
-		}
-
-		p := influxdb2.NewPoint(
-			"stock_prices",
-			map[string]string{
-				"ticker": strings.ReplaceAll(ticker, "-USD", "USDT"),
-			},
-			map[string]interface{}{
-				"open":      quoteData.Open[i],
-				"high":      quoteData.High[i],
-				"low":       quoteData.Low[i],
-				"close":     quoteData.Close[i],
-				"adj_close": adjCloseData[i],
-				"volume":    quoteData.Volume[i],
-			},
-			time.Unix(ts, 0),
-		)
-		points = append(points, p)
-	}
-	return points, nil
-}
-
 // --- Query Data Handler ---
 
 type DataQueryRequest struct {
@@ -418,6 +463,9 @@ func (s *Server) handleQueryData(c *gin.Context) {
 		req.Days = 252 // Default to 1 year of trading days
 	}
 
+	ctx, span := startSpan(c.Request.Context(), "handle_query_data", req.Ticker)
+	defer span.End()
+
 	// Build Flux query
 	query := fmt.Sprintf(`
 		from(bucket: "%s")
@@ -430,7 +478,7 @@ func (s *Server) handleQueryData(c *gin.Context) {
 
 	slog.Info("Querying InfluxDB", "ticker", req.Ticker, "days", req.Days)
 
-	result, err := s.QueryAPI.Query(context.Background(), query)
+	result, err := s.QueryAPI.Query(ctx, query)
 	if err != nil {
 		slog.Error("Query failed", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Query failed", "details": err.Error()})
@@ -20,6 +20,27 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+// --- Mock MarketDataProvider ---
+type MockProvider struct {
+	mock.Mock
+	name string
+}
+
+func (m *MockProvider) Name() string { return m.name }
+
+func (m *MockProvider) Supports(assetClass string) bool {
+	args := m.Called(assetClass)
+	return args.Bool(0)
+}
+
+func (m *MockProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	args := m.Called(ctx, symbol, start, end, interval)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*write.Point), args.Error(1)
+}
+
 // --- Mock InfluxDB QueryAPI ---
 type MockQueryAPI struct {
 	mock.Mock
@@ -89,13 +110,13 @@ func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
 }
 
 // --- Test Setup ---
-func setupTestRouter() (*gin.Engine, *MockWriteAPIBlocking, *MockQueryAPI, *MockHTTPClient) {
+func setupTestRouter() (*gin.Engine, *MockWriteAPIBlocking, *MockQueryAPI, *MockProvider) {
 	gin.SetMode(gin.TestMode)
 
 	// Create all mocks
 	mockWriteAPI := new(MockWriteAPIBlocking)
 	mockQueryAPI := new(MockQueryAPI)
-	mockHTTPClient := new(MockHTTPClient)
+	mockProvider := &MockProvider{name: "mock"}
 
 	// Setup default mock behaviors
 	// Return an error for Query to indicate "no existing data in DB"
@@ -103,40 +124,31 @@ func setupTestRouter() (*gin.Engine, *MockWriteAPIBlocking, *MockQueryAPI, *Mock
 	mockQueryAPI.On("Query", mock.Anything, mock.Anything).Return((*api.QueryTableResult)(nil), fmt.Errorf("no data")).Maybe()
 	mockWriteAPI.On("WritePoint", mock.Anything, mock.Anything).Return(nil).Maybe()
 
-	// Setup HTTP Mock to return FAKE Yahoo Data
-	fakeYahooResponse := `{
-		"chart": {
-			"result": [{
-				"meta": {"currency": "USD", "symbol": "SPY"},
-				"timestamp": [1672531200],
-				"indicators": {
-					"quote": [{"open": [100.0], "high": [105.0], "low": [99.0], "close": [102.0], "volume": [1000]}],
-					"adjclose": [{"adjclose": [102.0]}]
-				}
-			}],
-			"error": null
-		}
-	}`
+	mockProvider.On("Supports", mock.Anything).Return(true).Maybe()
+	mockProvider.On("FetchOHLCV", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]*write.Point{
+			influxdb2.NewPoint("stock_prices",
+				map[string]string{"ticker": "SPY"},
+				map[string]interface{}{"open": 100.0, "high": 105.0, "low": 99.0, "close": 102.0, "adj_close": 102.0, "volume": int64(1000)},
+				time.Unix(1672531200, 0),
+			),
+		}, nil).Maybe()
 
-	mockResp := &http.Response{
-		StatusCode: 200,
-		Body:       io.NopCloser(bytes.NewBufferString(fakeYahooResponse)),
-		Header:     make(http.Header),
-	}
-	mockHTTPClient.On("Do", mock.Anything).Return(mockResp, nil).Maybe()
+	registry := NewProviderRegistry()
+	registry.Register(mockProvider)
 
 	// Create Server with all mocks
 	server := &Server{
-		WriteAPI:   mockWriteAPI,
-		QueryAPI:   mockQueryAPI,
-		HTTPClient: mockHTTPClient,
+		WriteAPI:  mockWriteAPI,
+		QueryAPI:  mockQueryAPI,
+		Providers: registry,
 	}
 
 	router := gin.Default()
 	router.POST("/v1/data/fetch", server.handleFetchData)
 	router.POST("/v1/data/query", server.handleQueryData)
 
-	return router, mockWriteAPI, mockQueryAPI, mockHTTPClient
+	return router, mockWriteAPI, mockQueryAPI, mockProvider
 }
 
 // Helper function for tests that don't need mock access
@@ -353,17 +365,14 @@ func TestDateParsing_InvalidDate_FallsBack(t *testing.T) {
 	assert.WithinDuration(t, expected, parsed, 24*time.Hour)
 }
 
-// --- Tests for fetchYahooData ---
+// --- Tests for YahooProvider ---
 
-func TestFetchYahooData_StartTimeInFuture(t *testing.T) {
-	// Create a minimal server instance for testing
+func TestYahooProvider_StartTimeInFuture(t *testing.T) {
 	mockHTTPClient := new(MockHTTPClient)
-	server := &Server{
-		HTTPClient: mockHTTPClient,
-	}
+	provider := NewYahooProvider(mockHTTPClient)
 
 	futureTime := time.Now().Add(24 * time.Hour)
-	points, err := server.fetchYahooData("SPY", futureTime, "1d")
+	points, err := provider.FetchOHLCV(context.Background(), "SPY", futureTime, time.Now(), "1d")
 
 	assert.NoError(t, err)
 	assert.Nil(t, points)
@@ -404,7 +413,7 @@ func TestFetchYahooData_TickerSymbolReplacement(t *testing.T) {
 			// Get the tag value
 			tickerTag := point.TagList()[0].Value
 
-			// Note: The actual replacement happens in fetchYahooData at line 348
+			// Note: The actual replacement happens in YahooProvider.FetchOHLCV
 			// This is just demonstrating the pattern
 			assert.Contains(t, []string{tc.input, tc.expected}, tickerTag)
 		})
@@ -534,11 +543,7 @@ func TestDataPoint_AllFields(t *testing.T) {
 
 // --- Integration-Style Tests (with mocked external dependencies) ---
 
-func TestFetchYahooData_WithMockedHTTPClient_Success(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	mockWriteAPI := new(MockWriteAPIBlocking)
-	mockQueryAPI := new(MockQueryAPI)
+func TestYahooProvider_WithMockedHTTPClient_Success(t *testing.T) {
 	mockHTTPClient := new(MockHTTPClient)
 
 	// Setup successful Yahoo Finance response
@@ -569,14 +574,9 @@ func TestFetchYahooData_WithMockedHTTPClient_Success(t *testing.T) {
 	}
 	mockHTTPClient.On("Do", mock.Anything).Return(mockResp, nil)
 
-	server := &Server{
-		WriteAPI:   mockWriteAPI,
-		QueryAPI:   mockQueryAPI,
-		HTTPClient: mockHTTPClient,
-	}
+	provider := NewYahooProvider(mockHTTPClient)
 
-	// Test fetchYahooData
-	points, err := server.fetchYahooData("SPY", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "1d")
+	points, err := provider.FetchOHLCV(context.Background(), "SPY", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Now(), "1d")
 
 	assert.NoError(t, err)
 	assert.NotNil(t, points)
@@ -586,11 +586,7 @@ func TestFetchYahooData_WithMockedHTTPClient_Success(t *testing.T) {
 	mockHTTPClient.AssertExpectations(t)
 }
 
-func TestFetchYahooData_WithMockedHTTPClient_HTTPError(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	mockWriteAPI := new(MockWriteAPIBlocking)
-	mockQueryAPI := new(MockQueryAPI)
+func TestYahooProvider_WithMockedHTTPClient_HTTPError(t *testing.T) {
 	mockHTTPClient := new(MockHTTPClient)
 
 	// Setup error response
@@ -601,25 +597,16 @@ func TestFetchYahooData_WithMockedHTTPClient_HTTPError(t *testing.T) {
 	}
 	mockHTTPClient.On("Do", mock.Anything).Return(mockResp, nil)
 
-	server := &Server{
-		WriteAPI:   mockWriteAPI,
-		QueryAPI:   mockQueryAPI,
-		HTTPClient: mockHTTPClient,
-	}
+	provider := NewYahooProvider(mockHTTPClient)
 
-	// Test fetchYahooData with error
-	points, err := server.fetchYahooData("SPY", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "1d")
+	points, err := provider.FetchOHLCV(context.Background(), "SPY", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Now(), "1d")
 
 	assert.Error(t, err)
 	assert.Nil(t, points)
 	assert.Contains(t, err.Error(), "Yahoo API returned status")
 }
 
-func TestFetchYahooData_WithMockedHTTPClient_YahooAPIError(t *testing.T) {
-	gin.SetMode(gin.TestMode)
-
-	mockWriteAPI := new(MockWriteAPIBlocking)
-	mockQueryAPI := new(MockQueryAPI)
+func TestYahooProvider_WithMockedHTTPClient_YahooAPIError(t *testing.T) {
 	mockHTTPClient := new(MockHTTPClient)
 
 	// Setup Yahoo API error in response body
@@ -637,25 +624,51 @@ func TestFetchYahooData_WithMockedHTTPClient_YahooAPIError(t *testing.T) {
 	}
 	mockHTTPClient.On("Do", mock.Anything).Return(mockResp, nil)
 
-	server := &Server{
-		WriteAPI:   mockWriteAPI,
-		QueryAPI:   mockQueryAPI,
-		HTTPClient: mockHTTPClient,
-	}
+	provider := NewYahooProvider(mockHTTPClient)
 
-	// Test fetchYahooData with API error
-	points, err := server.fetchYahooData("INVALID", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "1d")
+	points, err := provider.FetchOHLCV(context.Background(), "INVALID", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Now(), "1d")
 
 	assert.Error(t, err)
 	assert.Nil(t, points)
 	assert.Contains(t, err.Error(), "Yahoo API error")
 }
 
+// --- Tests for ProviderRegistry ---
+
+func TestProviderRegistry_ResolveByName(t *testing.T) {
+	registry := NewProviderRegistry()
+	provider := &MockProvider{name: "mock"}
+	registry.Register(provider)
+
+	resolved, err := registry.Resolve("mock", "SPY")
+	assert.NoError(t, err)
+	assert.Equal(t, provider, resolved)
+}
+
+func TestProviderRegistry_ResolveUnknownName(t *testing.T) {
+	registry := NewProviderRegistry()
+	_, err := registry.Resolve("nope", "SPY")
+	assert.Error(t, err)
+}
+
+func TestProviderRegistry_ResolveFallbackChain(t *testing.T) {
+	registry := NewProviderRegistry()
+
+	equityOnly := &MockProvider{name: "equity-only"}
+	equityOnly.On("Supports", "equity").Return(true)
+
+	registry.Register(equityOnly)
+
+	resolved, err := registry.Resolve("", "AAPL")
+	assert.NoError(t, err)
+	assert.Equal(t, equityOnly, resolved)
+}
+
 // Note: Full end-to-end test removed because it's too complex for unit testing.
 // The flow involves QueryTableResult which has complex internal state that's
 // difficult to mock properly. The individual components are tested separately:
 // - Handler validation: TestHandleFetchData_* tests
-// - HTTP client mocking: TestFetchYahooData_WithMockedHTTPClient_* tests
+// - Provider mocking: TestYahooProvider_WithMockedHTTPClient_* tests
 // - Date parsing: TestDateParsing_* tests
 // - Data structures: TestDataFetchRequest_JSONMarshaling tests
 // Integration testing should be done separately with a real or containerized InfluxDB instance.
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// --- Prometheus metrics ---
+//
+// Registered once at package init via promauto and scraped from GET
+// /metrics. Labels are kept low-cardinality (ticker/provider/result) since
+// every additional label value multiplies the series Prometheus stores.
+
+var (
+	fetchRequestsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fetch_requests_total",
+			Help: "Total per-ticker fetch attempts, labeled by outcome.",
+		},
+		[]string{"ticker", "provider", "result"},
+	)
+
+	fetchDurationSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "fetch_duration_seconds",
+			Help:    "Wall-clock time to fetch and persist a single ticker.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"ticker", "provider"},
+	)
+
+	yahooHTTPDurationSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yahoo_http_duration_seconds",
+			Help:    "Latency of outbound HTTP calls to the Yahoo Finance chart API.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"result"},
+	)
+
+	influxWriteDurationSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "influx_write_duration_seconds",
+			Help:    "Latency of InfluxDB WritePoint calls.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"result"},
+	)
+
+	fetchJobsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fetch_jobs_in_flight",
+			Help: "Number of ticker fetch jobs currently being processed by workers.",
+		},
+	)
+)
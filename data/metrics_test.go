@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMetrics_Exposed handles a fetch request and then scrapes /metrics,
+// checking that fetch_requests_total picked up the handled request.
+func TestMetrics_Exposed(t *testing.T) {
+	router, _, _, _ := setupTestRouter()
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	fetchBody := `{"names": ["SPY"], "start_date": "2023-01-01", "interval": "1d"}`
+	fetchReq := httptest.NewRequest(http.MethodPost, "/v1/data/fetch", strings.NewReader(fetchBody))
+	fetchReq.Header.Set("Content-Type", "application/json")
+	fetchRec := httptest.NewRecorder()
+	router.ServeHTTP(fetchRec, fetchReq)
+	assert.Equal(t, http.StatusOK, fetchRec.Code)
+
+	metricsReq := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	metricsRec := httptest.NewRecorder()
+	router.ServeHTTP(metricsRec, metricsReq)
+
+	assert.Equal(t, http.StatusOK, metricsRec.Code)
+	assert.Contains(t, metricsRec.Body.String(), "fetch_requests_total")
+	assert.Contains(t, metricsRec.Body.String(), "fetch_duration_seconds")
+}
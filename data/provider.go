@@ -0,0 +1,783 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"golang.org/x/time/rate"
+)
+
+// --- MarketDataProvider ---
+// MarketDataProvider abstracts a single upstream source of OHLCV data so that
+// Yahoo is no longer the only option wired into the fetch path.
+type MarketDataProvider interface {
+	// FetchOHLCV returns points for symbol between start and end at the given
+	// interval, ready to be written to the "stock_prices" measurement.
+	FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error)
+	// Supports reports whether this provider can serve the given asset class
+	// (e.g. "equity", "crypto").
+	Supports(assetClass string) bool
+	// Name identifies the provider for logging, metrics, and selection.
+	Name() string
+}
+
+// assetClassForTicker is a best-effort classifier based on the ticker shape,
+// mirroring the heuristic the old code used for the "-USD" string replace.
+func assetClassForTicker(ticker string) string {
+	if strings.HasSuffix(ticker, "-USD") || strings.HasSuffix(ticker, "USDT") {
+		return "crypto"
+	}
+	return "equity"
+}
+
+// --- Rate limiting & retry ---
+
+// providerLimiter wraps a token-bucket limiter so each provider can be
+// throttled independently of the others.
+type providerLimiter struct {
+	limiter *rate.Limiter
+}
+
+func newProviderLimiter(requestsPerSecond float64, burst int) *providerLimiter {
+	return &providerLimiter{limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), burst)}
+}
+
+func (p *providerLimiter) wait(ctx context.Context) error {
+	if p == nil || p.limiter == nil {
+		return nil
+	}
+	return p.limiter.Wait(ctx)
+}
+
+// withRetry retries fn with exponential backoff, giving up after maxAttempts.
+func withRetry(ctx context.Context, maxAttempts int, fn func() error) error {
+	var lastErr error
+	backoff := 250 * time.Millisecond
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("all %d attempts failed: %w", maxAttempts, lastErr)
+}
+
+// --- ProviderRegistry ---
+
+// ProviderRegistry holds every configured MarketDataProvider and resolves
+// which one(s) should serve a given request.
+type ProviderRegistry struct {
+	providers map[string]MarketDataProvider
+	order     []string // fallback order when no provider is explicitly requested
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{providers: make(map[string]MarketDataProvider)}
+}
+
+// Register adds a provider under its own name and appends it to the fallback
+// order used by Resolve when a request doesn't name a provider.
+func (r *ProviderRegistry) Register(p MarketDataProvider) {
+	r.providers[p.Name()] = p
+	r.order = append(r.order, p.Name())
+}
+
+// Get returns the provider registered under name, if any.
+func (r *ProviderRegistry) Get(name string) (MarketDataProvider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Resolve picks the provider to use for a fetch. If requested names a
+// registered provider it is used directly; otherwise a MultiProvider fallback
+// chain is built from every registered provider that supports the ticker's
+// asset class, in registration order.
+func (r *ProviderRegistry) Resolve(requested string, ticker string) (MarketDataProvider, error) {
+	if requested != "" {
+		p, ok := r.Get(requested)
+		if !ok {
+			return nil, fmt.Errorf("unknown market data provider %q", requested)
+		}
+		return p, nil
+	}
+
+	assetClass := assetClassForTicker(ticker)
+	var chain []MarketDataProvider
+	for _, name := range r.order {
+		p := r.providers[name]
+		if p.Supports(assetClass) {
+			chain = append(chain, p)
+		}
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no provider registered for asset class %q", assetClass)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return &MultiProvider{providers: chain}, nil
+}
+
+// --- MultiProvider fallback chain ---
+
+// MultiProvider tries each underlying provider in order, falling through to
+// the next one on failure. It satisfies MarketDataProvider itself so it can
+// be dropped in anywhere a single provider is expected.
+type MultiProvider struct {
+	providers []MarketDataProvider
+}
+
+func (m *MultiProvider) Name() string {
+	return "multi"
+}
+
+func (m *MultiProvider) Supports(assetClass string) bool {
+	for _, p := range m.providers {
+		if p.Supports(assetClass) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MultiProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	var lastErr error
+	for _, p := range m.providers {
+		points, err := p.FetchOHLCV(ctx, symbol, start, end, interval)
+		if err == nil {
+			return points, nil
+		}
+		slog.Warn("provider failed, falling back", "provider", p.Name(), "symbol", symbol, "error", err)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all providers failed for %s: %w", symbol, lastErr)
+}
+
+// --- Yahoo Finance provider ---
+
+type YahooProvider struct {
+	HTTPClient HTTPClient
+	limiter    *providerLimiter
+}
+
+func NewYahooProvider(client HTTPClient) *YahooProvider {
+	return &YahooProvider{HTTPClient: client, limiter: newProviderLimiter(2, 4)}
+}
+
+func (y *YahooProvider) Name() string { return "yahoo" }
+
+func (y *YahooProvider) Supports(assetClass string) bool {
+	return assetClass == "equity" || assetClass == "crypto"
+}
+
+func (y *YahooProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	if start.After(end) {
+		return nil, nil // Start time is in the future, no data to fetch
+	}
+	if err := y.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(
+		"https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=%s&events=history",
+		symbol, start.Unix(), end.Unix(), interval,
+	)
+
+	var chartData YahooChartResponse
+	err := withRetry(ctx, 3, func() error {
+		httpCtx, span := startSpan(ctx, "yahoo_http_call", symbol)
+		defer span.End()
+
+		req, err := http.NewRequestWithContext(httpCtx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create the http request %w", err)
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+		start := time.Now()
+		resp, err := y.HTTPClient.Do(req)
+		httpResult := "success"
+		defer func() {
+			yahooHTTPDurationSeconds.WithLabelValues(httpResult).Observe(time.Since(start).Seconds())
+		}()
+		if err != nil {
+			httpResult = "error"
+			return fmt.Errorf("failed to call Yahoo API: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			httpResult = "error"
+			return fmt.Errorf("Yahoo API returned status %s", resp.Status)
+		}
+
+		chartData = YahooChartResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&chartData); err != nil {
+			httpResult = "error"
+			return fmt.Errorf("failed to decode Yahoo JSON: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if chartData.Chart.Error != nil {
+		return nil, fmt.Errorf("Yahoo API error: %v", chartData.Chart.Error)
+	}
+	if len(chartData.Chart.Result) == 0 {
+		return nil, fmt.Errorf("no results in Yahoo response for ticker %s", symbol)
+	}
+
+	res := chartData.Chart.Result[0]
+	if len(res.Indicators.AdjClose) == 0 || len(res.Indicators.Quote) == 0 {
+		return nil, fmt.Errorf("incomplete indicators in Yahoo response for ticker %s", symbol)
+	}
+
+	adjCloseData := res.Indicators.AdjClose[0].AdjClose
+	quoteData := res.Indicators.Quote[0]
+
+	var points []*write.Point
+	for i, ts := range res.Timestamp {
+		if len(adjCloseData) <= i ||
+			len(quoteData.Close) <= i ||
+			len(quoteData.Open) <= i ||
+			len(quoteData.High) <= i ||
+			len(quoteData.Low) <= i ||
+			len(quoteData.Volume) <= i {
+			slog.Warn("Skipping incomplete data point", "ticker", symbol, "timestamp", ts)
+			continue
+		}
+
+		p := influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{
+				"ticker": strings.ReplaceAll(symbol, "-USD", "USDT"),
+			},
+			map[string]interface{}{
+				"open":      quoteData.Open[i],
+				"high":      quoteData.High[i],
+				"low":       quoteData.Low[i],
+				"close":     quoteData.Close[i],
+				"adj_close": adjCloseData[i],
+				"volume":    quoteData.Volume[i],
+			},
+			time.Unix(ts, 0),
+		)
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// --- Binance provider ---
+
+// BinanceProvider fetches crypto klines directly from Binance, which is a
+// better fit than Yahoo for "-USD"/"USDT" tickers.
+type BinanceProvider struct {
+	HTTPClient HTTPClient
+	limiter    *providerLimiter
+}
+
+func NewBinanceProvider(client HTTPClient) *BinanceProvider {
+	return &BinanceProvider{HTTPClient: client, limiter: newProviderLimiter(5, 10)}
+}
+
+func (b *BinanceProvider) Name() string { return "binance" }
+
+func (b *BinanceProvider) Supports(assetClass string) bool { return assetClass == "crypto" }
+
+// binanceSymbol turns "BTC-USD" into Binance's "BTCUSDT" pairing.
+func binanceSymbol(ticker string) string {
+	return strings.ReplaceAll(ticker, "-USD", "USDT")
+}
+
+// binanceInterval maps our "1d"/"1h"/"1m" style intervals onto Binance's.
+func binanceInterval(interval string) string {
+	switch interval {
+	case "1d", "1h", "1m":
+		return interval
+	default:
+		return "1d"
+	}
+}
+
+type binanceKline []interface{}
+
+func (b *BinanceProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	if start.After(end) {
+		return nil, nil
+	}
+	if err := b.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf(
+		"https://api.binance.com/api/v3/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d&limit=1000",
+		binanceSymbol(symbol), binanceInterval(interval), start.UnixMilli(), end.UnixMilli(),
+	)
+
+	var klines []binanceKline
+	err := withRetry(ctx, 3, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create the http request %w", err)
+		}
+
+		resp, err := b.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call Binance API: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Binance API returned status %s", resp.Status)
+		}
+
+		klines = nil
+		if err := json.NewDecoder(resp.Body).Decode(&klines); err != nil {
+			return fmt.Errorf("failed to decode Binance JSON: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*write.Point, 0, len(klines))
+	for _, k := range klines {
+		// [openTime, open, high, low, close, volume, closeTime, ...]
+		if len(k) < 6 {
+			continue
+		}
+		openTimeMs, ok := k[0].(float64)
+		if !ok {
+			continue
+		}
+		open, _ := strconv.ParseFloat(fmt.Sprint(k[1]), 64)
+		high, _ := strconv.ParseFloat(fmt.Sprint(k[2]), 64)
+		low, _ := strconv.ParseFloat(fmt.Sprint(k[3]), 64)
+		closePrice, _ := strconv.ParseFloat(fmt.Sprint(k[4]), 64)
+		volume, _ := strconv.ParseFloat(fmt.Sprint(k[5]), 64)
+
+		p := influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{"ticker": binanceSymbol(symbol)},
+			map[string]interface{}{
+				"open":      open,
+				"high":      high,
+				"low":       low,
+				"close":     closePrice,
+				"adj_close": closePrice,
+				"volume":    int64(volume),
+			},
+			time.UnixMilli(int64(openTimeMs)),
+		)
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// --- Alpha Vantage provider ---
+
+type AlphaVantageProvider struct {
+	HTTPClient HTTPClient
+	APIKey     string
+	limiter    *providerLimiter
+}
+
+func NewAlphaVantageProvider(client HTTPClient) *AlphaVantageProvider {
+	// Alpha Vantage's free tier is capped around 5 requests/minute.
+	return &AlphaVantageProvider{
+		HTTPClient: client,
+		APIKey:     os.Getenv("ALPHAVANTAGE_API_KEY"),
+		limiter:    newProviderLimiter(5.0/60.0, 1),
+	}
+}
+
+func (a *AlphaVantageProvider) Name() string { return "alphavantage" }
+
+func (a *AlphaVantageProvider) Supports(assetClass string) bool { return assetClass == "equity" }
+
+func (a *AlphaVantageProvider) alphaVantageFunction(interval string) (function string, avInterval string) {
+	switch interval {
+	case "1d":
+		return "TIME_SERIES_DAILY_ADJUSTED", ""
+	default:
+		return "TIME_SERIES_INTRADAY", interval
+	}
+}
+
+type alphaVantageDailyBar struct {
+	Open   string `json:"1. open"`
+	High   string `json:"2. high"`
+	Low    string `json:"3. low"`
+	Close  string `json:"4. close"`
+	Adj    string `json:"5. adjusted close"`
+	Volume string `json:"6. volume"`
+}
+
+func (a *AlphaVantageProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	if start.After(end) {
+		return nil, nil
+	}
+	if a.APIKey == "" {
+		return nil, fmt.Errorf("ALPHAVANTAGE_API_KEY not set")
+	}
+	if err := a.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	function, avInterval := a.alphaVantageFunction(interval)
+	url := fmt.Sprintf("https://www.alphavantage.co/query?function=%s&symbol=%s&outputsize=full&apikey=%s",
+		function, symbol, a.APIKey)
+	if avInterval != "" {
+		url += "&interval=" + avInterval
+	}
+
+	var raw map[string]json.RawMessage
+	err := withRetry(ctx, 3, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create the http request %w", err)
+		}
+
+		resp, err := a.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call Alpha Vantage API: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Alpha Vantage API returned status %s", resp.Status)
+		}
+
+		raw = nil
+		if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+			return fmt.Errorf("failed to decode Alpha Vantage JSON: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var seriesKey string
+	for key := range raw {
+		if strings.Contains(key, "Time Series") {
+			seriesKey = key
+			break
+		}
+	}
+	if seriesKey == "" {
+		return nil, fmt.Errorf("no time series in Alpha Vantage response for ticker %s", symbol)
+	}
+
+	var series map[string]alphaVantageDailyBar
+	if err := json.Unmarshal(raw[seriesKey], &series); err != nil {
+		return nil, fmt.Errorf("failed to decode Alpha Vantage series: %w", err)
+	}
+
+	var points []*write.Point
+	for ts, bar := range series {
+		barTime, err := time.Parse("2006-01-02", ts)
+		if err != nil {
+			barTime, err = time.Parse("2006-01-02 15:04:05", ts)
+			if err != nil {
+				continue
+			}
+		}
+		if barTime.Before(start) || barTime.After(end) {
+			continue
+		}
+		open, _ := strconv.ParseFloat(bar.Open, 64)
+		high, _ := strconv.ParseFloat(bar.High, 64)
+		low, _ := strconv.ParseFloat(bar.Low, 64)
+		closePrice, _ := strconv.ParseFloat(bar.Close, 64)
+		adjClose, _ := strconv.ParseFloat(bar.Adj, 64)
+		if adjClose == 0 {
+			adjClose = closePrice
+		}
+		volume, _ := strconv.ParseInt(bar.Volume, 10, 64)
+
+		p := influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{"ticker": symbol},
+			map[string]interface{}{
+				"open":      open,
+				"high":      high,
+				"low":       low,
+				"close":     closePrice,
+				"adj_close": adjClose,
+				"volume":    volume,
+			},
+			barTime,
+		)
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// --- IEX / Polygon provider ---
+
+// IEXProvider fetches aggregated bars from Polygon's IEX-sourced aggregates
+// endpoint, giving equities a source independent of Yahoo and Alpha Vantage.
+type IEXProvider struct {
+	HTTPClient HTTPClient
+	APIKey     string
+	limiter    *providerLimiter
+}
+
+func NewIEXProvider(client HTTPClient) *IEXProvider {
+	return &IEXProvider{
+		HTTPClient: client,
+		APIKey:     os.Getenv("POLYGON_API_KEY"),
+		limiter:    newProviderLimiter(5, 5),
+	}
+}
+
+func (p *IEXProvider) Name() string { return "iex" }
+
+func (p *IEXProvider) Supports(assetClass string) bool { return assetClass == "equity" }
+
+// polygonTimespan maps our interval strings onto Polygon's aggregate timespans.
+func polygonTimespan(interval string) (multiplier int, timespan string) {
+	switch interval {
+	case "1h":
+		return 1, "hour"
+	case "1m":
+		return 1, "minute"
+	default:
+		return 1, "day"
+	}
+}
+
+type polygonAggsResponse struct {
+	Results []struct {
+		Open   float64 `json:"o"`
+		High   float64 `json:"h"`
+		Low    float64 `json:"l"`
+		Close  float64 `json:"c"`
+		Volume float64 `json:"v"`
+		Time   int64   `json:"t"` // ms since epoch
+	} `json:"results"`
+}
+
+func (p *IEXProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	if start.After(end) {
+		return nil, nil
+	}
+	if p.APIKey == "" {
+		return nil, fmt.Errorf("POLYGON_API_KEY not set")
+	}
+	if err := p.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	multiplier, timespan := polygonTimespan(interval)
+	url := fmt.Sprintf(
+		"https://api.polygon.io/v2/aggs/ticker/%s/range/%d/%s/%s/%s?adjusted=true&sort=asc&limit=50000&apiKey=%s",
+		symbol, multiplier, timespan, start.Format("2006-01-02"), end.Format("2006-01-02"), p.APIKey,
+	)
+
+	var aggs polygonAggsResponse
+	err := withRetry(ctx, 3, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create the http request %w", err)
+		}
+
+		resp, err := p.HTTPClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to call Polygon API: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("Polygon API returned status %s", resp.Status)
+		}
+
+		aggs = polygonAggsResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(&aggs); err != nil {
+			return fmt.Errorf("failed to decode Polygon JSON: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	points := make([]*write.Point, 0, len(aggs.Results))
+	for _, bar := range aggs.Results {
+		pt := influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{"ticker": symbol},
+			map[string]interface{}{
+				"open":      bar.Open,
+				"high":      bar.High,
+				"low":       bar.Low,
+				"close":     bar.Close,
+				"adj_close": bar.Close,
+				"volume":    int64(bar.Volume),
+			},
+			time.UnixMilli(bar.Time),
+		)
+		points = append(points, pt)
+	}
+	return points, nil
+}
+
+// --- Alpaca provider ---
+
+// AlpacaProvider fetches bars from Alpaca's stock bars REST endpoint, giving
+// equities a source that isn't rate limited the way the unofficial Yahoo
+// endpoint is. Crypto is not served.
+type AlpacaProvider struct {
+	HTTPClient HTTPClient
+	APIKeyID   string
+	APISecret  string
+	limiter    *providerLimiter
+}
+
+func NewAlpacaProvider(client HTTPClient) *AlpacaProvider {
+	return &AlpacaProvider{
+		HTTPClient: client,
+		APIKeyID:   os.Getenv("ALPACA_API_KEY_ID"),
+		APISecret:  os.Getenv("ALPACA_API_SECRET_KEY"),
+		limiter:    newProviderLimiter(3, 3),
+	}
+}
+
+func (a *AlpacaProvider) Name() string { return "alpaca" }
+
+func (a *AlpacaProvider) Supports(assetClass string) bool {
+	return assetClass == "equity"
+}
+
+// alpacaTimeframe maps our interval strings onto Alpaca's timeframe query param.
+func alpacaTimeframe(interval string) string {
+	switch interval {
+	case "1h":
+		return "1Hour"
+	case "1m":
+		return "1Min"
+	default:
+		return "1Day"
+	}
+}
+
+type alpacaBar struct {
+	Time   string  `json:"t"`
+	Open   float64 `json:"o"`
+	High   float64 `json:"h"`
+	Low    float64 `json:"l"`
+	Close  float64 `json:"c"`
+	Volume float64 `json:"v"`
+}
+
+type alpacaBarsResponse struct {
+	Bars          []alpacaBar `json:"bars"`
+	NextPageToken string      `json:"next_page_token"`
+}
+
+func (a *AlpacaProvider) FetchOHLCV(ctx context.Context, symbol string, start, end time.Time, interval string) ([]*write.Point, error) {
+	if start.After(end) {
+		return nil, nil
+	}
+	if a.APIKeyID == "" || a.APISecret == "" {
+		return nil, fmt.Errorf("ALPACA_API_KEY_ID / ALPACA_API_SECRET_KEY not set")
+	}
+
+	var points []*write.Point
+	pageToken := ""
+	for {
+		if err := a.limiter.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		url := fmt.Sprintf(
+			"https://data.alpaca.markets/v2/stocks/%s/bars?timeframe=%s&start=%s&end=%s&limit=10000",
+			symbol, alpacaTimeframe(interval), start.Format(time.RFC3339), end.Format(time.RFC3339),
+		)
+		if pageToken != "" {
+			url += "&page_token=" + pageToken
+		}
+
+		var page alpacaBarsResponse
+		err := withRetry(ctx, 3, func() error {
+			req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create the http request %w", err)
+			}
+			req.Header.Set("APCA-API-KEY-ID", a.APIKeyID)
+			req.Header.Set("APCA-API-SECRET-KEY", a.APISecret)
+
+			resp, err := a.HTTPClient.Do(req)
+			if err != nil {
+				return fmt.Errorf("failed to call Alpaca API: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("Alpaca API returned status %s", resp.Status)
+			}
+
+			page = alpacaBarsResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+				return fmt.Errorf("failed to decode Alpaca JSON: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, bar := range page.Bars {
+			barTime, err := time.Parse(time.RFC3339, bar.Time)
+			if err != nil {
+				continue
+			}
+			pt := influxdb2.NewPoint(
+				"stock_prices",
+				map[string]string{"ticker": symbol},
+				map[string]interface{}{
+					"open":      bar.Open,
+					"high":      bar.High,
+					"low":       bar.Low,
+					"close":     bar.Close,
+					"adj_close": bar.Close,
+					"volume":    int64(bar.Volume),
+				},
+				barTime,
+			)
+			points = append(points, pt)
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+
+	return points, nil
+}
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/robfig/cron/v3"
+)
+
+// --- Scheduled fetch jobs ---
+//
+// The Scheduler lets clients register recurring fetch jobs instead of
+// poking POST /v1/data/fetch from an external cron. Job definitions are
+// persisted to InfluxDB so they survive a restart; each job then runs on
+// its own goroutine using the same worker pool as an on-demand fetch.
+
+const (
+	schedulerJobsMeasurement = "scheduler_jobs"
+	schedulerRunsMeasurement = "scheduler_runs"
+	schedulerRunHistorySize  = 20
+)
+
+// JobRequest is the body of POST /v1/jobs and POST /v1/schedules.
+type JobRequest struct {
+	Name          string   `json:"name"`
+	Tickers       []string `json:"tickers"`
+	StartDate     string   `json:"start_date"`
+	Interval      string   `json:"interval"`
+	Cron          string   `json:"cron"` // standard 5-field cron expression
+	Provider      string   `json:"provider,omitempty"`
+	Concurrency   int      `json:"concurrency,omitempty"`
+	JitterSeconds int      `json:"jitter_seconds,omitempty"` // max random delay added to each run, to avoid a thundering herd
+}
+
+// Job is the persisted, resolved form of a JobRequest returned to clients.
+type Job struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Tickers       []string  `json:"tickers"`
+	StartDate     string    `json:"start_date"`
+	Interval      string    `json:"interval"`
+	Cron          string    `json:"cron"`
+	Provider      string    `json:"provider,omitempty"`
+	Concurrency   int       `json:"concurrency"`
+	JitterSeconds int       `json:"jitter_seconds,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	NextRun       time.Time `json:"next_run"`
+	LastRunAt     time.Time `json:"last_run_at,omitempty"`
+	LastRunStatus string    `json:"last_run_status,omitempty"`
+}
+
+// JobRun records the outcome of a single execution of a job.
+type JobRun struct {
+	JobID      string            `json:"job_id"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Status     string            `json:"status"` // "success" or "error"
+	Details    map[string]string `json:"details,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Scheduler owns every registered recurring job and the goroutine driving
+// each one.
+type Scheduler struct {
+	server   *Server
+	writeAPI api.WriteAPIBlocking
+
+	mu     sync.Mutex
+	jobs   map[string]*runningJob
+	byName map[string]string // job name -> job ID, for name-addressed /v1/schedules routes
+}
+
+type runningJob struct {
+	Job
+	schedule cron.Schedule
+	cancel   context.CancelFunc
+
+	mu   sync.Mutex
+	runs []JobRun // most recent runs, newest first
+}
+
+func NewScheduler(server *Server, writeAPI api.WriteAPIBlocking) *Scheduler {
+	return &Scheduler{
+		server:   server,
+		writeAPI: writeAPI,
+		jobs:     make(map[string]*runningJob),
+		byName:   make(map[string]string),
+	}
+}
+
+// CreateJob validates req, persists it, and starts its recurring execution.
+func (s *Scheduler) CreateJob(req JobRequest) (*Job, error) {
+	if len(req.Tickers) == 0 {
+		return nil, fmt.Errorf("at least one ticker is required")
+	}
+	schedule, err := cron.ParseStandard(req.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", req.Cron, err)
+	}
+	if req.Interval == "" {
+		req.Interval = "1d"
+	}
+	if req.Concurrency <= 0 {
+		req.Concurrency = NUM_WORKERS
+	}
+
+	job := Job{
+		ID:            fmt.Sprintf("%s-%d", req.Name, time.Now().UnixNano()),
+		Name:          req.Name,
+		Tickers:       req.Tickers,
+		StartDate:     req.StartDate,
+		Interval:      req.Interval,
+		Cron:          req.Cron,
+		Provider:      req.Provider,
+		Concurrency:   req.Concurrency,
+		JitterSeconds: req.JitterSeconds,
+		CreatedAt:     time.Now(),
+	}
+	job.NextRun = schedule.Next(job.CreatedAt)
+
+	s.persistDefinition(job)
+	s.start(job, schedule)
+	return &job, nil
+}
+
+// LoadPersistedJobs restores every job definition previously written to
+// InfluxDB by persistDefinition and restarts its recurring execution, so
+// registered schedules survive a server restart instead of silently
+// disappearing.
+func (s *Scheduler) LoadPersistedJobs(ctx context.Context) error {
+	if s.server == nil || s.server.QueryAPI == nil {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+        from(bucket: "%s")
+          |> range(start: -5y)
+          |> filter(fn: (r) => r._measurement == "%s")
+          |> pivot(rowKey: ["_time", "job_id"], columnKey: ["_field"], valueColumn: "_value")
+          |> group(columns: ["job_id"])
+          |> last()
+    `, influxBucket, schedulerJobsMeasurement)
+
+	result, err := s.server.QueryAPI.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for result.Next() {
+		record := result.Record()
+		jobID, _ := record.ValueByKey("job_id").(string)
+		cronExpr, _ := record.ValueByKey("cron").(string)
+
+		schedule, err := cron.ParseStandard(cronExpr)
+		if err != nil {
+			slog.Error("failed to parse persisted job's cron expression, skipping", "job_id", jobID, "cron", cronExpr, "error", err)
+			continue
+		}
+
+		var tickers []string
+		if tickersJSON, ok := record.ValueByKey("tickers").(string); ok {
+			if err := json.Unmarshal([]byte(tickersJSON), &tickers); err != nil {
+				slog.Error("failed to unmarshal persisted job's tickers, skipping", "job_id", jobID, "error", err)
+				continue
+			}
+		}
+		concurrency, _ := record.ValueByKey("concurrency").(int64)
+		jitterSeconds, _ := record.ValueByKey("jitter_seconds").(int64)
+		name, _ := record.ValueByKey("name").(string)
+		provider, _ := record.ValueByKey("provider").(string)
+		startDate, _ := record.ValueByKey("start_date").(string)
+		interval, _ := record.ValueByKey("interval").(string)
+
+		job := Job{
+			ID:            jobID,
+			Name:          name,
+			Tickers:       tickers,
+			StartDate:     startDate,
+			Interval:      interval,
+			Cron:          cronExpr,
+			Provider:      provider,
+			Concurrency:   int(concurrency),
+			JitterSeconds: int(jitterSeconds),
+			CreatedAt:     record.Time(),
+		}
+		job.NextRun = schedule.Next(time.Now())
+
+		s.start(job, schedule)
+		slog.Info("restored scheduled job from InfluxDB", "job_id", job.ID, "name", job.Name)
+	}
+	return result.Err()
+}
+
+// start launches the goroutine that fires job on its cron schedule until the
+// job is cancelled.
+func (s *Scheduler) start(job Job, schedule cron.Schedule) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rj := &runningJob{Job: job, schedule: schedule, cancel: cancel}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = rj
+	s.byName[job.Name] = job.ID
+	s.mu.Unlock()
+
+	go s.loop(ctx, rj)
+}
+
+func (s *Scheduler) loop(ctx context.Context, rj *runningJob) {
+	for {
+		next := rj.schedule.Next(time.Now())
+		if rj.JitterSeconds > 0 {
+			next = next.Add(time.Duration(rand.Intn(rj.JitterSeconds+1)) * time.Second)
+		}
+		rj.mu.Lock()
+		rj.NextRun = next
+		rj.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.runOnce(ctx, rj)
+		}
+	}
+}
+
+// runOnce executes a single run of the job, records its outcome, and
+// returns it.
+func (s *Scheduler) runOnce(ctx context.Context, rj *runningJob) JobRun {
+	run := JobRun{JobID: rj.ID, StartedAt: time.Now()}
+
+	details := s.server.runFetchCycle(ctx, rj.Tickers, rj.StartDate, rj.Interval, rj.Provider, rj.Concurrency)
+	run.FinishedAt = time.Now()
+	run.Details = details
+	run.Status = "success"
+	for _, v := range details {
+		if len(v) >= 6 && v[:6] == "Error:" {
+			run.Status = "error"
+			run.Error = v
+			break
+		}
+	}
+
+	rj.mu.Lock()
+	rj.runs = append([]JobRun{run}, rj.runs...)
+	if len(rj.runs) > schedulerRunHistorySize {
+		rj.runs = rj.runs[:schedulerRunHistorySize]
+	}
+	rj.LastRunAt = run.StartedAt
+	rj.LastRunStatus = run.Status
+	rj.mu.Unlock()
+
+	s.persistRun(run)
+	return run
+}
+
+// Get returns a snapshot of a job by ID.
+func (s *Scheduler) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rj, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	rj.mu.Lock()
+	job := rj.Job
+	rj.mu.Unlock()
+	return &job, true
+}
+
+// List returns a snapshot of every registered job.
+func (s *Scheduler) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, rj := range s.jobs {
+		rj.mu.Lock()
+		jobs = append(jobs, rj.Job)
+		rj.mu.Unlock()
+	}
+	return jobs
+}
+
+// GetByName returns a snapshot of a job by name.
+func (s *Scheduler) GetByName(name string) (*Job, bool) {
+	s.mu.Lock()
+	id, ok := s.byName[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return s.Get(id)
+}
+
+// Runs returns the recent run history for a job, newest first.
+func (s *Scheduler) Runs(id string) ([]JobRun, bool) {
+	s.mu.Lock()
+	rj, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+	runs := make([]JobRun, len(rj.runs))
+	copy(runs, rj.runs)
+	return runs, true
+}
+
+// Delete stops and removes a job.
+func (s *Scheduler) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rj, ok := s.jobs[id]
+	if !ok {
+		return false
+	}
+	rj.cancel()
+	delete(s.jobs, id)
+	if s.byName[rj.Name] == id {
+		delete(s.byName, rj.Name)
+	}
+	return true
+}
+
+// DeleteByName stops and removes a job by name.
+func (s *Scheduler) DeleteByName(name string) bool {
+	s.mu.Lock()
+	id, ok := s.byName[name]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+	return s.Delete(id)
+}
+
+// TriggerByName runs a registered job immediately, out of band from its
+// cron schedule, and returns the resulting run.
+func (s *Scheduler) TriggerByName(ctx context.Context, name string) (*JobRun, error) {
+	s.mu.Lock()
+	id, ok := s.byName[name]
+	var rj *runningJob
+	if ok {
+		rj, ok = s.jobs[id]
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no schedule named %q", name)
+	}
+
+	run := s.runOnce(ctx, rj)
+	return &run, nil
+}
+
+// persistDefinition writes the job definition to InfluxDB so it can be
+// restored after a restart.
+func (s *Scheduler) persistDefinition(job Job) {
+	if s.writeAPI == nil {
+		return
+	}
+	tickersJSON, err := json.Marshal(job.Tickers)
+	if err != nil {
+		slog.Error("failed to marshal job tickers", "job_id", job.ID, "error", err)
+		return
+	}
+	point := influxdb2.NewPoint(
+		schedulerJobsMeasurement,
+		map[string]string{"job_id": job.ID},
+		map[string]interface{}{
+			"name":           job.Name,
+			"tickers":        string(tickersJSON),
+			"start_date":     job.StartDate,
+			"interval":       job.Interval,
+			"cron":           job.Cron,
+			"provider":       job.Provider,
+			"concurrency":    int64(job.Concurrency),
+			"jitter_seconds": int64(job.JitterSeconds),
+		},
+		job.CreatedAt,
+	)
+	if err := s.writeAPI.WritePoint(context.Background(), point); err != nil {
+		slog.Error("failed to persist job definition", "job_id", job.ID, "error", err)
+	}
+}
+
+// persistRun writes a run-history event to InfluxDB.
+func (s *Scheduler) persistRun(run JobRun) {
+	if s.writeAPI == nil {
+		return
+	}
+	detailsJSON, err := json.Marshal(run.Details)
+	if err != nil {
+		slog.Error("failed to marshal run details", "job_id", run.JobID, "error", err)
+		return
+	}
+	point := influxdb2.NewPoint(
+		schedulerRunsMeasurement,
+		map[string]string{"job_id": run.JobID, "status": run.Status},
+		map[string]interface{}{
+			"details":          string(detailsJSON),
+			"error":            run.Error,
+			"duration_seconds": run.FinishedAt.Sub(run.StartedAt).Seconds(),
+		},
+		run.StartedAt,
+	)
+	if err := s.writeAPI.WritePoint(context.Background(), point); err != nil {
+		slog.Error("failed to persist job run", "job_id", run.JobID, "error", err)
+	}
+}
+
+// --- HTTP handlers ---
+
+func (s *Server) handleCreateJob(c *gin.Context) {
+	var req JobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	job, err := s.Scheduler.CreateJob(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, job)
+}
+
+func (s *Server) handleGetJob(c *gin.Context) {
+	job, ok := s.Scheduler.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) handleDeleteJob(c *gin.Context) {
+	if !s.Scheduler.Delete(c.Param("id")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (s *Server) handleGetJobRuns(c *gin.Context) {
+	runs, ok := s.Scheduler.Runs(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": c.Param("id"), "runs": runs})
+}
+
+// --- Name-addressed /v1/schedules routes ---
+//
+// These mutate the same underlying Scheduler as /v1/jobs, but are addressed
+// by the human-chosen job name rather than the generated ID, matching how
+// operators think about recurring ingestion jobs.
+
+func (s *Server) handleListSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"schedules": s.Scheduler.List()})
+}
+
+func (s *Server) handleDeleteSchedule(c *gin.Context) {
+	if !s.Scheduler.DeleteByName(c.Param("name")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (s *Server) handleRunSchedule(c *gin.Context) {
+	run, err := s.Scheduler.TriggerByName(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestScheduler() (*Scheduler, *MockProvider) {
+	provider := &MockProvider{name: "mock"}
+	provider.On("Supports", mock.Anything).Return(true).Maybe()
+	provider.On("FetchOHLCV", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]*write.Point{}, nil).Maybe()
+
+	registry := NewProviderRegistry()
+	registry.Register(provider)
+
+	// QueryAPI is intentionally left nil here: getLatestTimestamp now
+	// guards against that (see main.go) and falls back to the requested
+	// start date, same as a Server wired to a real InfluxDB with no prior
+	// data for the ticker.
+	server := &Server{Providers: registry}
+	scheduler := NewScheduler(server, nil)
+	server.Scheduler = scheduler
+	return scheduler, provider
+}
+
+func TestScheduler_LoadPersistedJobs_NilQueryAPIIsNoOp(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	err := scheduler.LoadPersistedJobs(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, scheduler.jobs, 0)
+}
+
+func TestScheduler_CreateJob_InvalidCron(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	_, err := scheduler.CreateJob(JobRequest{
+		Name:    "bad",
+		Tickers: []string{"SPY"},
+		Cron:    "not a cron expression",
+	})
+
+	assert.Error(t, err)
+}
+
+func TestScheduler_CreateJob_NoTickers(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	_, err := scheduler.CreateJob(JobRequest{
+		Name: "empty",
+		Cron: "0 * * * *",
+	})
+
+	assert.Error(t, err)
+}
+
+func TestScheduler_CreateJob_Success(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	job, err := scheduler.CreateJob(JobRequest{
+		Name:      "daily-spy",
+		Tickers:   []string{"SPY"},
+		StartDate: "2020-01-01",
+		Cron:      "0 0 * * *",
+	})
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, job.ID)
+	assert.Equal(t, "1d", job.Interval) // defaulted
+	assert.False(t, job.NextRun.IsZero())
+
+	defer scheduler.Delete(job.ID)
+
+	got, ok := scheduler.Get(job.ID)
+	assert.True(t, ok)
+	assert.Equal(t, job.Name, got.Name)
+}
+
+func TestScheduler_DeleteRemovesJob(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	job, err := scheduler.CreateJob(JobRequest{
+		Name:    "to-delete",
+		Tickers: []string{"SPY"},
+		Cron:    "0 0 * * *",
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, scheduler.Delete(job.ID))
+
+	_, ok := scheduler.Get(job.ID)
+	assert.False(t, ok)
+}
+
+func TestScheduler_RunOnceRecordsHistory(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	job, err := scheduler.CreateJob(JobRequest{
+		Name:      "history",
+		Tickers:   []string{"SPY"},
+		StartDate: "2020-01-01",
+		Cron:      "0 0 * * *",
+	})
+	assert.NoError(t, err)
+	defer scheduler.Delete(job.ID)
+
+	scheduler.mu.Lock()
+	rj := scheduler.jobs[job.ID]
+	scheduler.mu.Unlock()
+
+	scheduler.runOnce(context.Background(), rj)
+
+	runs, ok := scheduler.Runs(job.ID)
+	assert.True(t, ok)
+	assert.Len(t, runs, 1)
+	assert.Equal(t, "success", runs[0].Status)
+}
+
+func TestScheduler_GetRuns_UnknownJob(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	_, ok := scheduler.Runs("does-not-exist")
+	assert.False(t, ok)
+
+	_, ok = scheduler.Get("does-not-exist")
+	assert.False(t, ok)
+}
+
+func TestScheduler_ByNameRoutes(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	job, err := scheduler.CreateJob(JobRequest{
+		Name:      "by-name",
+		Tickers:   []string{"SPY"},
+		StartDate: "2020-01-01",
+		Cron:      "0 0 * * *",
+	})
+	assert.NoError(t, err)
+	defer scheduler.DeleteByName(job.Name)
+
+	got, ok := scheduler.GetByName(job.Name)
+	assert.True(t, ok)
+	assert.Equal(t, job.ID, got.ID)
+
+	run, err := scheduler.TriggerByName(context.Background(), job.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, "success", run.Status)
+
+	got, ok = scheduler.GetByName(job.Name)
+	assert.True(t, ok)
+	assert.Equal(t, "success", got.LastRunStatus)
+	assert.False(t, got.LastRunAt.IsZero())
+
+	list := scheduler.List()
+	assert.Len(t, list, 1)
+	assert.Equal(t, job.Name, list[0].Name)
+
+	assert.True(t, scheduler.DeleteByName(job.Name))
+	_, ok = scheduler.GetByName(job.Name)
+	assert.False(t, ok)
+}
+
+func TestScheduler_TriggerByName_UnknownName(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	_, err := scheduler.TriggerByName(context.Background(), "does-not-exist")
+	assert.Error(t, err)
+}
+
+func TestScheduler_CreateJob_KeepsJitterSeconds(t *testing.T) {
+	scheduler, _ := newTestScheduler()
+
+	job, err := scheduler.CreateJob(JobRequest{
+		Name:          "jittery",
+		Tickers:       []string{"SPY"},
+		StartDate:     "2020-01-01",
+		Cron:          "0 0 * * *",
+		JitterSeconds: 5,
+	})
+	assert.NoError(t, err)
+	defer scheduler.Delete(job.ID)
+
+	assert.Eventually(t, func() bool {
+		scheduler.mu.Lock()
+		rj := scheduler.jobs[job.ID]
+		scheduler.mu.Unlock()
+		rj.mu.Lock()
+		defer rj.mu.Unlock()
+		return rj.JitterSeconds == 5
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestScheduler_PersistDefinition_WritesJitterSeconds(t *testing.T) {
+	mockWriteAPI := new(MockWriteAPIBlocking)
+	var written *write.Point
+	mockWriteAPI.On("WritePoint", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			points := args.Get(1).([]*write.Point)
+			written = points[0]
+		}).
+		Return(nil)
+	scheduler := NewScheduler(&Server{}, mockWriteAPI)
+
+	scheduler.persistDefinition(Job{ID: "job-1", Cron: "0 0 * * *", JitterSeconds: 5})
+
+	fields := map[string]interface{}{}
+	for _, f := range written.FieldList() {
+		fields[f.Key] = f.Value
+	}
+	assert.Equal(t, int64(5), fields["jitter_seconds"])
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api"
+)
+
+// --- Tick ---
+
+// Tick is a single live price update for a ticker.
+type Tick struct {
+	Ticker string    `json:"ticker"`
+	Price  float64   `json:"price"`
+	Volume int64     `json:"volume"`
+	Time   time.Time `json:"time"`
+}
+
+// TickSource is anything that can stream live ticks for a ticker. It's
+// implementable by a real exchange WebSocket (Binance, Polygon) or by a
+// synthetic poller over a batch MarketDataProvider like Yahoo.
+type TickSource interface {
+	Subscribe(ctx context.Context, ticker string) (<-chan Tick, error)
+}
+
+// --- Synthetic poller ---
+
+// PollingTickSource turns a ProviderRegistry into a TickSource by repeatedly
+// resolving and fetching the most recent bar for each subscribed ticker and
+// emitting it as a tick. This is the fallback used for providers (like
+// Yahoo) that have no native streaming API.
+type PollingTickSource struct {
+	Providers *ProviderRegistry
+	Interval  time.Duration
+}
+
+func NewPollingTickSource(providers *ProviderRegistry, interval time.Duration) *PollingTickSource {
+	return &PollingTickSource{Providers: providers, Interval: interval}
+}
+
+func (p *PollingTickSource) Subscribe(ctx context.Context, ticker string) (<-chan Tick, error) {
+	out := make(chan Tick)
+	go func() {
+		defer close(out)
+		poll := time.NewTicker(p.Interval)
+		defer poll.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-poll.C:
+				tick, ok := p.fetchLatestTick(ctx, ticker)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (p *PollingTickSource) fetchLatestTick(ctx context.Context, ticker string) (Tick, bool) {
+	provider, err := p.Providers.Resolve("", ticker)
+	if err != nil {
+		slog.Warn("polling tick source has no provider", "ticker", ticker, "error", err)
+		return Tick{}, false
+	}
+
+	end := time.Now()
+	start := end.Add(-2 * p.Interval)
+	points, err := provider.FetchOHLCV(ctx, ticker, start, end, "1m")
+	if err != nil {
+		slog.Warn("polling tick source fetch failed", "ticker", ticker, "error", err)
+		return Tick{}, false
+	}
+	if len(points) == 0 {
+		return Tick{}, false
+	}
+	last := points[len(points)-1]
+
+	var price float64
+	var volume int64
+	for _, f := range last.FieldList() {
+		switch f.Key {
+		case "close":
+			if v, ok := f.Value.(float64); ok {
+				price = v
+			}
+		case "volume":
+			switch v := f.Value.(type) {
+			case int64:
+				volume = v
+			case float64:
+				volume = int64(v)
+			}
+		}
+	}
+	return Tick{Ticker: ticker, Price: price, Volume: volume, Time: last.Time()}, true
+}
+
+// --- stream client ---
+
+// subscriptionMessage is the JSON control protocol clients use to manage
+// their subscription set over the websocket.
+type subscriptionMessage struct {
+	Action  string   `json:"action"` // "subscribe" or "unsubscribe"
+	Tickers []string `json:"tickers"`
+}
+
+// streamClient wraps a websocket connection and its outbound tick buffer.
+type streamClient struct {
+	conn    *websocket.Conn
+	send    chan Tick
+	dropped int64 // count of ticks dropped because send was full (slow client)
+
+	mu      sync.Mutex
+	tickers map[string]bool
+}
+
+func newStreamClient(conn *websocket.Conn) *streamClient {
+	return &streamClient{
+		conn:    conn,
+		send:    make(chan Tick, 64),
+		tickers: make(map[string]bool),
+	}
+}
+
+// deliver pushes a tick to the client without blocking; a full buffer means
+// the client is too slow to keep up, so we drop the tick and count it.
+func (c *streamClient) deliver(t Tick) {
+	select {
+	case c.send <- t:
+	default:
+		atomic.AddInt64(&c.dropped, 1)
+	}
+}
+
+// --- StreamManager ---
+
+const (
+	streamWriteWait  = 10 * time.Second
+	streamPingPeriod = 30 * time.Second
+	streamPongWait   = 60 * time.Second
+)
+
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// StreamManager fans ticks from per-ticker TickSource subscriptions out to
+// every websocket client subscribed to that ticker, and persists every tick
+// it sees into InfluxDB alongside the batch OHLCV data.
+type StreamManager struct {
+	source TickSource
+	writer api.WriteAPIBlocking
+
+	mu      sync.Mutex
+	topics  map[string]map[*streamClient]bool
+	cancels map[string]context.CancelFunc
+}
+
+func NewStreamManager(source TickSource, writer api.WriteAPIBlocking) *StreamManager {
+	return &StreamManager{
+		source:  source,
+		writer:  writer,
+		topics:  make(map[string]map[*streamClient]bool),
+		cancels: make(map[string]context.CancelFunc),
+	}
+}
+
+// subscribe adds client to ticker's topic, starting the upstream TickSource
+// for that ticker the first time anyone subscribes to it.
+func (m *StreamManager) subscribe(client *streamClient, ticker string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client.mu.Lock()
+	client.tickers[ticker] = true
+	client.mu.Unlock()
+
+	clients, ok := m.topics[ticker]
+	if !ok {
+		clients = make(map[*streamClient]bool)
+		m.topics[ticker] = clients
+		m.startTopic(ticker)
+	}
+	clients[client] = true
+}
+
+// unsubscribe removes client from ticker's topic, stopping the upstream
+// TickSource once the last subscriber leaves.
+func (m *StreamManager) unsubscribe(client *streamClient, ticker string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client.mu.Lock()
+	delete(client.tickers, ticker)
+	client.mu.Unlock()
+
+	clients, ok := m.topics[ticker]
+	if !ok {
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(m.topics, ticker)
+		if cancel, ok := m.cancels[ticker]; ok {
+			cancel()
+			delete(m.cancels, ticker)
+		}
+	}
+}
+
+// removeClient unsubscribes client from every topic it's in, used on
+// disconnect.
+func (m *StreamManager) removeClient(client *streamClient) {
+	client.mu.Lock()
+	tickers := make([]string, 0, len(client.tickers))
+	for t := range client.tickers {
+		tickers = append(tickers, t)
+	}
+	client.mu.Unlock()
+
+	for _, t := range tickers {
+		m.unsubscribe(client, t)
+	}
+}
+
+// startTopic launches the background goroutine that reads ticks from the
+// TickSource for ticker and fans them out to subscribed clients. Caller must
+// hold m.mu.
+func (m *StreamManager) startTopic(ticker string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancels[ticker] = cancel
+
+	ticks, err := m.source.Subscribe(ctx, ticker)
+	if err != nil {
+		slog.Error("failed to subscribe to tick source", "ticker", ticker, "error", err)
+		cancel()
+		delete(m.cancels, ticker)
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case tick, ok := <-ticks:
+				if !ok {
+					return
+				}
+				m.fanOut(ticker, tick)
+				m.persist(ticker, tick)
+			}
+		}
+	}()
+}
+
+func (m *StreamManager) fanOut(ticker string, tick Tick) {
+	m.mu.Lock()
+	clients := m.topics[ticker]
+	recipients := make([]*streamClient, 0, len(clients))
+	for c := range clients {
+		recipients = append(recipients, c)
+	}
+	m.mu.Unlock()
+
+	for _, c := range recipients {
+		c.deliver(tick)
+	}
+}
+
+// persist writes the tick to InfluxDB without blocking the fan-out path.
+func (m *StreamManager) persist(ticker string, tick Tick) {
+	if m.writer == nil {
+		return
+	}
+	go func() {
+		point := influxdb2.NewPoint(
+			"stock_prices",
+			map[string]string{"ticker": ticker},
+			map[string]interface{}{"close": tick.Price, "volume": tick.Volume},
+			tick.Time,
+		)
+		if err := m.writer.WritePoint(context.Background(), point); err != nil {
+			slog.Error("failed to persist live tick", "ticker", ticker, "error", err)
+		}
+	}()
+}
+
+// --- HTTP handler ---
+
+// handleStream upgrades GET /v1/data/stream to a websocket and fans out live
+// ticks to whatever tickers the client subscribes to over the JSON control
+// protocol: {"action":"subscribe","tickers":["SPY","BTC-USD"]}.
+func (s *Server) handleStream(c *gin.Context) {
+	conn, err := streamUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.Error("failed to upgrade websocket", "error", err)
+		return
+	}
+
+	client := newStreamClient(conn)
+	defer func() {
+		s.Streams.removeClient(client)
+		conn.Close()
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(streamPongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		return nil
+	})
+
+	done := make(chan struct{})
+	go s.streamWriteLoop(client, done)
+	s.streamReadLoop(client)
+	close(done)
+}
+
+func (s *Server) streamReadLoop(client *streamClient) {
+	for {
+		_, raw, err := client.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg subscriptionMessage
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			slog.Warn("invalid stream subscription message", "error", err)
+			continue
+		}
+		switch msg.Action {
+		case "subscribe":
+			for _, ticker := range msg.Tickers {
+				s.Streams.subscribe(client, ticker)
+			}
+		case "unsubscribe":
+			for _, ticker := range msg.Tickers {
+				s.Streams.unsubscribe(client, ticker)
+			}
+		default:
+			slog.Warn("unknown stream action", "action", msg.Action)
+		}
+	}
+}
+
+func (s *Server) streamWriteLoop(client *streamClient, done <-chan struct{}) {
+	pingTicker := time.NewTicker(streamPingPeriod)
+	defer pingTicker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case tick := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := client.conn.WriteJSON(tick); err != nil {
+				return
+			}
+		case <-pingTicker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
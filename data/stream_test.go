@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStreamManager_SubscribeStartsTopic(t *testing.T) {
+	source := &fakeTickSource{ticks: make(chan Tick, 1)}
+	manager := NewStreamManager(source, nil)
+	client := newStreamClient(&websocket.Conn{})
+
+	manager.subscribe(client, "SPY")
+
+	manager.mu.Lock()
+	_, subscribed := manager.topics["SPY"][client]
+	manager.mu.Unlock()
+
+	assert.True(t, subscribed)
+	assert.Equal(t, 1, source.subscribeCalls())
+}
+
+func TestStreamManager_UnsubscribeStopsTopicWhenEmpty(t *testing.T) {
+	source := &fakeTickSource{ticks: make(chan Tick, 1)}
+	manager := NewStreamManager(source, nil)
+	client := newStreamClient(&websocket.Conn{})
+
+	manager.subscribe(client, "SPY")
+	manager.unsubscribe(client, "SPY")
+
+	manager.mu.Lock()
+	_, stillTracked := manager.topics["SPY"]
+	manager.mu.Unlock()
+
+	assert.False(t, stillTracked)
+}
+
+func TestStreamManager_FanOutDeliversToSubscribedClients(t *testing.T) {
+	source := &fakeTickSource{ticks: make(chan Tick, 1)}
+	manager := NewStreamManager(source, nil)
+	client := newStreamClient(&websocket.Conn{})
+
+	manager.subscribe(client, "SPY")
+	manager.fanOut("SPY", Tick{Ticker: "SPY", Price: 100})
+
+	select {
+	case tick := <-client.send:
+		assert.Equal(t, "SPY", tick.Ticker)
+		assert.Equal(t, 100.0, tick.Price)
+	case <-time.After(time.Second):
+		t.Fatal("expected a delivered tick")
+	}
+}
+
+func TestStreamClient_DeliverDropsWhenBufferFull(t *testing.T) {
+	client := newStreamClient(&websocket.Conn{})
+	client.send = make(chan Tick, 1)
+
+	client.deliver(Tick{Ticker: "SPY"})
+	client.deliver(Tick{Ticker: "SPY"}) // buffer is full, should be dropped
+
+	assert.Equal(t, int64(1), client.dropped)
+}
+
+func TestStreamManager_RemoveClientUnsubscribesEverything(t *testing.T) {
+	source := &fakeTickSource{ticks: make(chan Tick, 1)}
+	manager := NewStreamManager(source, nil)
+	client := newStreamClient(&websocket.Conn{})
+
+	manager.subscribe(client, "SPY")
+	manager.subscribe(client, "AAPL")
+	manager.removeClient(client)
+
+	manager.mu.Lock()
+	_, spyTracked := manager.topics["SPY"]
+	_, aaplTracked := manager.topics["AAPL"]
+	manager.mu.Unlock()
+
+	assert.False(t, spyTracked)
+	assert.False(t, aaplTracked)
+}
+
+// --- fake TickSource ---
+
+type fakeTickSource struct {
+	ticks chan Tick
+	calls int
+}
+
+func (f *fakeTickSource) subscribeCalls() int { return f.calls }
+
+func (f *fakeTickSource) Subscribe(ctx context.Context, ticker string) (<-chan Tick, error) {
+	f.calls++
+	return f.ticks, nil
+}
+
+func TestPollingTickSource_FetchLatestTick(t *testing.T) {
+	provider := &MockProvider{name: "mock"}
+	provider.On("Supports", mock.Anything).Return(true)
+	provider.On("FetchOHLCV", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]*write.Point{
+		influxdb2.NewPoint("stock_prices",
+			map[string]string{"ticker": "SPY"},
+			map[string]interface{}{"close": 101.5, "volume": int64(500)},
+			time.Now(),
+		),
+	}, nil)
+
+	registry := NewProviderRegistry()
+	registry.Register(provider)
+
+	source := NewPollingTickSource(registry, time.Second)
+	tick, ok := source.fetchLatestTick(context.Background(), "SPY")
+
+	assert.True(t, ok)
+	assert.Equal(t, 101.5, tick.Price)
+	assert.Equal(t, int64(500), tick.Volume)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer emits spans covering the fetch path: HTTP call -> JSON decode ->
+// Influx write. Spans are attached to the context.Context threaded down
+// from handleFetchData's request context (not context.Background()), so a
+// single POST /v1/data/fetch produces one connected trace.
+var tracer = otel.Tracer("sapheneia/data")
+
+// initTracing wires an SDK TracerProvider so spans created via startSpan
+// are actually exported instead of being dropped by otel's no-op default.
+// Spans print as JSON to stdout, same as this service's slog logs, unless
+// OTEL_TRACES_DISABLED is set (e.g. in tests), in which case tracing stays
+// a no-op.
+func initTracing() (shutdown func(context.Context) error, err error) {
+	if os.Getenv("OTEL_TRACES_DISABLED") != "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithoutTimestamps())
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// startSpan tags every span with the ticker it's working on, since that's
+// the main dimension traces get filtered by in the backend.
+func startSpan(ctx context.Context, name, ticker string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attribute.String("ticker", ticker)))
+}
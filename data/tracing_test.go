@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitTracing_DisabledIsNoOp(t *testing.T) {
+	t.Setenv("OTEL_TRACES_DISABLED", "1")
+
+	shutdown, err := initTracing()
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInitTracing_WiresTracerProvider(t *testing.T) {
+	t.Setenv("OTEL_TRACES_DISABLED", "")
+
+	shutdown, err := initTracing()
+	assert.NoError(t, err)
+	defer shutdown(context.Background())
+
+	_, span := startSpan(context.Background(), "test_span", "SPY")
+	defer span.End()
+	assert.True(t, span.SpanContext().IsValid())
+}